@@ -0,0 +1,91 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/kelseyhightower/envconfig"
+)
+
+// backendConfig configures a named vault backend. Variables
+// are read with the backend prefix, for example
+// PROD_VAULT_ADDR. The addresses, token and namespace must
+// be set per backend. The tls, auth type, renewal and
+// consistency settings fall back to the unprefixed variable
+// when unset. The auth method settings are read by the auth
+// method, and never fall back.
+type backendConfig struct {
+	VaultAddr          string        `split_words:"true"`
+	VaultFailoverAddrs []string      `split_words:"true"`
+	VaultStandbyAddr   string        `split_words:"true"`
+	VaultToken         string        `split_words:"true"`
+	VaultNamespace     string        `split_words:"true"`
+	CACert             string        `envconfig:"VAULT_CACERT"`
+	CAPath             string        `envconfig:"VAULT_CAPATH"`
+	ClientCert         string        `envconfig:"VAULT_CLIENT_CERT"`
+	ClientKey          string        `envconfig:"VAULT_CLIENT_KEY"`
+	SkipVerify         bool          `envconfig:"VAULT_SKIP_VERIFY"`
+	TLSServerName      string        `envconfig:"VAULT_TLS_SERVER_NAME"`
+	AuthType           string        `envconfig:"VAULT_AUTH_TYPE"`
+	Renew              time.Duration `envconfig:"VAULT_TOKEN_RENEWAL"`
+	ReadYourWrites     bool          `envconfig:"VAULT_READ_YOUR_WRITES"`
+}
+
+// helper function returns the environment variable prefix
+// of the named backend.
+func backendPrefix(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// helper function reads the named backend configuration
+// from the environment and creates the vault client.
+func newBackend(name string) (*api.Client, *backendConfig, error) {
+	spec := new(backendConfig)
+	err := envconfig.Process(backendPrefix(name), spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	if spec.VaultAddr == "" && len(spec.VaultFailoverAddrs) != 0 {
+		spec.VaultAddr = spec.VaultFailoverAddrs[0]
+	}
+	if spec.VaultAddr == "" {
+		return nil, nil, fmt.Errorf("missing vault address for backend %s", name)
+	}
+
+	config := api.DefaultConfig()
+	config.Address = spec.VaultAddr
+	err = config.ConfigureTLS(&api.TLSConfig{
+		CACert:        spec.CACert,
+		CAPath:        spec.CAPath,
+		ClientCert:    spec.ClientCert,
+		ClientKey:     spec.ClientKey,
+		TLSServerName: spec.TLSServerName,
+		Insecure:      spec.SkipVerify,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the client reads the global VAULT_TOKEN and
+	// VAULT_NAMESPACE, which belong to the default
+	// backend, and are replaced here.
+	client.SetToken(spec.VaultToken)
+	client.ClearNamespace()
+	if spec.VaultNamespace != "" {
+		client.SetNamespace(spec.VaultNamespace)
+	}
+	if spec.ReadYourWrites || spec.VaultStandbyAddr != "" {
+		client.SetReadYourWrites(true)
+	}
+	return client, spec, nil
+}
@@ -7,19 +7,34 @@ package main
 import (
 	"context"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/drone/drone-go/plugin/secret"
 	"github.com/drone/drone-vault/plugin"
+	"github.com/drone/drone-vault/plugin/admin"
+	"github.com/drone/drone-vault/plugin/cache"
+	"github.com/drone/drone-vault/plugin/failover"
+	"github.com/drone/drone-vault/plugin/health"
 	"github.com/drone/drone-vault/plugin/token"
-	"github.com/drone/drone-vault/plugin/token/approle"
-	"github.com/drone/drone-vault/plugin/token/kubernetes"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
 
+	_ "github.com/drone/drone-vault/plugin/token/approle"
+	_ "github.com/drone/drone-vault/plugin/token/aws"
+	_ "github.com/drone/drone-vault/plugin/token/azure"
+	_ "github.com/drone/drone-vault/plugin/token/cert"
+	_ "github.com/drone/drone-vault/plugin/token/file"
+	_ "github.com/drone/drone-vault/plugin/token/gcp"
+	_ "github.com/drone/drone-vault/plugin/token/jwt"
+	_ "github.com/drone/drone-vault/plugin/token/kubernetes"
+	_ "github.com/drone/drone-vault/plugin/token/userpass"
 	_ "github.com/joho/godotenv/autoload"
 )
 
@@ -30,25 +45,42 @@ var envs = []string{
 	"VAULT_CACERT",
 	"VAULT_CAPATH",
 	"VAULT_CLIENT_CERT",
+	"VAULT_CLIENT_KEY",
 	"VAULT_SKIP_VERIFY",
 	"VAULT_MAX_RETRIES",
+	"VAULT_NAMESPACE",
 	"VAULT_TOKEN",
 	"VAULT_TLS_SERVER_NAME",
 }
 
 type config struct {
-	Address            string        `envconfig:"DRONE_BIND"`
-	Debug              bool          `envconfig:"DRONE_DEBUG"`
-	Secret             string        `envconfig:"DRONE_SECRET"`
-	DisallowForks      bool          `envconfig:"DRONE_DISALLOW_FORKS"`
-	VaultAddr          string        `envconfig:"VAULT_ADDR"`
-	VaultRenew         time.Duration `envconfig:"VAULT_TOKEN_RENEWAL"`
-	VaultTTL           time.Duration `envconfig:"VAULT_TOKEN_TTL"`
-	VaultAuthType      string        `envconfig:"VAULT_AUTH_TYPE"`
-	VaultAuthMount     string        `envconfig:"VAULT_AUTH_MOUNT_POINT"`
-	VaultApproleID     string        `envconfig:"VAULT_APPROLE_ID"`
-	VaultApproleSecret string        `envconfig:"VAULT_APPROLE_SECRET"`
-	VaultKubeRole      string        `envconfig:"VAULT_KUBERNETES_ROLE"`
+	Address          string            `envconfig:"DRONE_BIND"`
+	Debug            bool              `envconfig:"DRONE_DEBUG"`
+	Secret           string            `envconfig:"DRONE_SECRET"`
+	DisallowForks    bool              `envconfig:"DRONE_DISALLOW_FORKS"`
+	CacheTTL         time.Duration     `envconfig:"DRONE_CACHE_TTL"`
+	CacheStaleTTL    time.Duration     `envconfig:"DRONE_CACHE_STALE_TTL"`
+	CacheNegativeTTL time.Duration     `envconfig:"DRONE_CACHE_NEGATIVE_TTL"`
+	CachePath        string            `envconfig:"DRONE_CACHE_PATH"`
+	CacheKey         string            `envconfig:"DRONE_CACHE_KEY"`
+	CacheSize        int               `envconfig:"DRONE_CACHE_SIZE"`
+	CacheWarmFile    string            `envconfig:"DRONE_CACHE_WARM_FILE"`
+	AdminTokens      map[string]string `envconfig:"DRONE_ADMIN_TOKENS"`
+	ShutdownTimeout  time.Duration     `envconfig:"DRONE_SHUTDOWN_TIMEOUT"`
+	VaultAddr        string            `envconfig:"VAULT_ADDR"`
+	VaultFailover    []string          `envconfig:"VAULT_FAILOVER_ADDRS"`
+	VaultProbe       time.Duration     `envconfig:"VAULT_FAILOVER_INTERVAL"`
+	VaultRenew       time.Duration     `envconfig:"VAULT_TOKEN_RENEWAL"`
+	VaultAuthType    string            `envconfig:"VAULT_AUTH_TYPE"`
+	VaultNamespace   string            `envconfig:"VAULT_NAMESPACE"`
+	VaultNamespaces  map[string]string `envconfig:"VAULT_NAMESPACES"`
+	VaultCheck       time.Duration     `envconfig:"VAULT_TOKEN_CHECK_INTERVAL"`
+	VaultCheckStrict bool              `envconfig:"VAULT_TOKEN_CHECK_STRICT"`
+	VaultBackends    []string          `envconfig:"VAULT_BACKENDS"`
+	VaultPrefixes    map[string]string `envconfig:"VAULT_BACKEND_PREFIXES"`
+	VaultConsistent  bool              `envconfig:"VAULT_READ_YOUR_WRITES"`
+	VaultStandby     string            `envconfig:"VAULT_STANDBY_ADDR"`
+	VaultActive      []string          `envconfig:"VAULT_ACTIVE_PREFIXES"`
 }
 
 func main() {
@@ -61,15 +93,31 @@ func main() {
 	if spec.Debug {
 		logrus.SetLevel(logrus.DebugLevel)
 	}
+
+	// purges the on-disk secret cache and exits.
+	if len(os.Args) > 1 && os.Args[1] == "purge" {
+		if spec.CachePath == "" {
+			logrus.Fatalln("missing cache path")
+		}
+		if err := cache.Purge(spec.CachePath); err != nil {
+			logrus.Fatalln(err)
+		}
+		logrus.Infof("secret cache purged: %s", spec.CachePath)
+		return
+	}
+
 	if spec.Secret == "" {
 		logrus.Fatalln("missing secret key")
 	}
-	if spec.VaultAddr == "" {
+	if spec.VaultAddr == "" && len(spec.VaultFailover) == 0 {
 		logrus.Warnln("missing vault address")
 	}
 	if spec.Address == "" {
 		spec.Address = ":3000"
 	}
+	if spec.ShutdownTimeout == 0 {
+		spec.ShutdownTimeout = 30 * time.Second
+	}
 
 	// creates the vault client from the VAULT_*
 	// environment variables.
@@ -78,71 +126,312 @@ func main() {
 		logrus.Fatalln(err)
 	}
 
-	// global context
-	ctx := context.Background()
+	// global context, canceled when the process receives
+	// an interrupt or termination signal.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// the client authenticates, and by default reads
+	// secrets, in the global namespace. VAULT_NAMESPACE is
+	// also read by the client, and is set here for clarity.
+	if spec.VaultNamespace != "" {
+		logrus.Infof("vault namespace: %s", spec.VaultNamespace)
+		client.SetNamespace(spec.VaultNamespace)
+	}
 
 	if spec.DisallowForks {
 		logrus.Info("globally disallowing secrets in forks")
 	}
 
+	var opts []plugin.Option
+	if spec.CacheTTL != 0 || spec.CacheStaleTTL != 0 {
+		logrus.Infof("secret caching enabled: %v max age", spec.CacheTTL)
+		c := cache.NewMemory()
+
+		// the cache is persisted to disk, encrypted with the
+		// cache key or, if unset, a key derived from the
+		// shared secret.
+		if spec.CachePath != "" {
+			key := spec.CacheKey
+			if key == "" {
+				key = spec.Secret
+			}
+			c, err = cache.NewDisk(spec.CachePath, []byte(key), spec.CacheSize)
+			if err != nil {
+				logrus.Fatalln(err)
+			}
+			logrus.Infof("secret cache persisted to %s", spec.CachePath)
+		}
+		opts = append(opts, plugin.WithCache(c, spec.CacheTTL))
+	}
+	if spec.CacheNegativeTTL != 0 {
+		logrus.Infof("caching secrets not found: %v max age", spec.CacheNegativeTTL)
+		opts = append(opts, plugin.WithNegativeCache(spec.CacheNegativeTTL))
+	}
+	if spec.CacheStaleTTL != 0 {
+		logrus.Infof("serving stale secrets on error: %v max staleness", spec.CacheStaleTTL)
+		opts = append(opts, plugin.WithStaleIfError(spec.CacheStaleTTL))
+	}
+
+	// secret reads can be sent to the performance standby
+	// nodes. the client records the replication state of
+	// its own writes, such as token logins, and sends the
+	// state with each read, so that a standby that has not
+	// caught up forwards the read to the active node.
+	if spec.VaultStandby != "" {
+		opts = append(opts, standbyOption("", spec.VaultStandby))
+		spec.VaultConsistent = true
+	}
+	if spec.VaultConsistent {
+		logrus.Infoln("vault read-your-writes consistency enabled")
+		client.SetReadYourWrites(true)
+	}
+	if len(spec.VaultActive) != 0 {
+		logrus.Infof("vault active node reads enabled: %v", spec.VaultActive)
+		opts = append(opts, plugin.WithActivePrefixes(spec.VaultActive))
+	}
+
+	if len(spec.VaultNamespaces) != 0 {
+		logrus.Infof("vault namespace routing enabled: %d namespaces", len(spec.VaultNamespaces))
+		opts = append(opts, plugin.WithNamespaces(spec.VaultNamespaces))
+	}
+
+	// named vault backends, for example separate clusters
+	// for production and non-production secrets, each with
+	// its own address, tls configuration and auth method.
+	type backend struct {
+		name   string
+		client *api.Client
+		spec   *backendConfig
+	}
+	var backends []backend
+	for _, name := range spec.VaultBackends {
+		client, config, err := newBackend(name)
+		if err != nil {
+			logrus.Fatalln(err)
+		}
+		logrus.Infof("vault backend %s: %s", name, config.VaultAddr)
+		opts = append(opts, plugin.WithBackend(name, client))
+		if config.VaultStandbyAddr != "" {
+			opts = append(opts, standbyOption(name, config.VaultStandbyAddr))
+		}
+		backends = append(backends, backend{name, client, config})
+	}
+	if len(spec.VaultPrefixes) != 0 {
+		for prefix, name := range spec.VaultPrefixes {
+			logrus.Infof("vault backend %s: routing path prefix %s", name, prefix)
+		}
+		opts = append(opts, plugin.WithBackendPrefixes(spec.VaultPrefixes))
+	}
+
+	p := plugin.New(client, spec.DisallowForks, opts...)
+
 	http.Handle("/", secret.Handler(
 		spec.Secret,
-		plugin.New(client, spec.DisallowForks),
+		p,
 		logrus.StandardLogger(),
 	))
 
-	var g errgroup.Group
+	// the admin api is used to inspect and purge the secret
+	// cache, and is only enabled if admin tokens are defined.
+	if len(spec.AdminTokens) != 0 {
+		logrus.Info("admin api enabled")
+		http.Handle("/admin/cache", admin.Handler(
+			spec.AdminTokens,
+			p,
+		))
+	}
+
+	// the group context is canceled on shutdown, or when
+	// the server fails, stopping all background tasks.
+	g, ctx := errgroup.WithContext(ctx)
+
+	// the vault endpoints are probed in order, and the
+	// client is pointed at the first healthy endpoint, so
+	// that reads fail over when the primary is unavailable.
+	// the endpoints must belong to the same cluster, since
+	// the token is shared.
+	var checks []health.Checker
+	if len(spec.VaultFailover) != 0 {
+		checks = append(checks, startFailover(ctx, g, client, spec.VaultFailover, spec.VaultProbe))
+	}
 
 	// the token can be fetched at runtime if an auth
-	// provider is configured. otherwise, the user must
-	// specify a VAULT_TOKEN.
-	if spec.VaultAuthType == kubernetes.Name {
-		renewer := kubernetes.NewRenewer(
-			client,
-			spec.VaultAddr,
-			spec.VaultKubeRole,
-			spec.VaultAuthMount,
-		)
-		err := renewer.Renew(ctx)
+	// method is configured. otherwise, the user must
+	// specify a VAULT_TOKEN. each auth method reads its
+	// own configuration from the environment.
+	factory, err := token.Lookup(spec.VaultAuthType)
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+	auth, err := factory(client, "")
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+	renewer := token.NewRenewer(auth, spec.VaultRenew)
+	if err := renewer.Login(ctx); err != nil {
+		logrus.WithError(err).
+			Warnln("vault: cannot request token, retrying in background")
+	}
+
+	// the token is looked up at startup to report its
+	// policies and ttl, and to detect an invalid token
+	// before secrets are requested.
+	monitor := token.NewMonitor(client, spec.VaultCheck)
+	if _, err := monitor.Check(ctx); err != nil && spec.VaultCheckStrict {
+		logrus.Fatalln("vault: refusing to start with an invalid token")
+	}
+
+	// each named backend authenticates with its own auth
+	// method, which reads its configuration from variables
+	// with the backend prefix, and renews its own token.
+	checks = append(checks, renewer, monitor)
+	renewers := []*token.Renewer{renewer}
+	for _, b := range backends {
+		b := b
+		prefix := backendPrefix(b.name)
+		if len(b.spec.VaultFailoverAddrs) != 0 {
+			f := startFailover(ctx, g, b.client, b.spec.VaultFailoverAddrs, spec.VaultProbe)
+			checks = append(checks, health.Named(b.name, f))
+		}
+		factory, err := token.Lookup(b.spec.AuthType)
 		if err != nil {
 			logrus.Fatalln(err)
 		}
-
-		// the vault token needs to be periodically
-		// refreshed and the kubernetes token has a
-		// max age of 32 days.
+		auth, err := factory(b.client, prefix)
+		if err != nil {
+			logrus.Fatalln(err)
+		}
+		renewer := token.NewRenewer(auth, b.spec.Renew)
+		if err := renewer.Login(ctx); err != nil {
+			logrus.WithError(err).
+				WithField("backend", b.name).
+				Warnln("vault: cannot request token, retrying in background")
+		}
+		monitor := token.NewMonitor(b.client, spec.VaultCheck)
+		if _, err := monitor.Check(ctx); err != nil && spec.VaultCheckStrict {
+			logrus.Fatalf("vault: refusing to start with an invalid token for backend %s", b.name)
+		}
 		g.Go(func() error {
-			return renewer.Run(ctx, spec.VaultRenew)
+			return monitor.Run(ctx)
 		})
-	} else if spec.VaultAuthType == approle.Name {
-		renewer := approle.NewRenewer(
-			client,
-			spec.VaultApproleID,
-			spec.VaultApproleSecret,
-			spec.VaultTTL,
+		g.Go(func() error {
+			return renewer.Run(ctx)
+		})
+		checks = append(checks,
+			health.Named(b.name, renewer),
+			health.Named(b.name, monitor),
 		)
-		err := renewer.Renew(ctx)
+		renewers = append(renewers, renewer)
+	}
+
+	// the health endpoint reports whether the plugin holds
+	// a valid token, and is unhealthy while login or token
+	// renewal is failing, or the token check fails.
+	// the cache status reports the number of stale secrets
+	// served while vault is sealed or unreachable.
+	if spec.CacheStaleTTL != 0 {
+		checks = append(checks, p)
+	}
+	http.Handle("/healthz", health.Handler(checks...))
+
+	// the detailed health status, including the token
+	// policies and vault errors, is only served to admins.
+	if len(spec.AdminTokens) != 0 {
+		http.Handle("/admin/health", admin.Protect(
+			spec.AdminTokens,
+			health.DetailHandler(checks...),
+		))
+	}
+
+	// the token is checked periodically, so that an expired
+	// or revoked token is reported by the health endpoint.
+	g.Go(func() error {
+		return monitor.Run(ctx)
+	})
+
+	// the vault token needs to be periodically refreshed
+	// before it expires, and a new token is requested when
+	// the token can no longer be renewed.
+	g.Go(func() error {
+		return renewer.Run(ctx)
+	})
+
+	// prefetches frequently used secrets into the cache
+	// and refreshes them before they expire.
+	if spec.CacheWarmFile != "" {
+		if spec.CacheTTL == 0 {
+			logrus.Fatalln("cache warming requires DRONE_CACHE_TTL")
+		}
+		paths, err := plugin.LoadPaths(spec.CacheWarmFile)
 		if err != nil {
 			logrus.Fatalln(err)
 		}
-
-		// the vault token needs to be periodically refreshed
-		g.Go(func() error {
-			return renewer.Run(ctx, spec.VaultRenew)
-		})
-	} else {
 		g.Go(func() error {
-			return token.NewRenewer(
-				client, spec.VaultTTL, spec.VaultRenew).Run(ctx)
+			return p.Warm(ctx, paths)
 		})
 	}
 
+	server := &http.Server{Addr: spec.Address}
 	g.Go(func() error {
 		logrus.Infof("server listening on address %s", spec.Address)
-		return http.ListenAndServe(spec.Address, nil)
+		err := server.ListenAndServe()
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		return err
 	})
 
-	if err := g.Wait(); err != nil {
+	// on shutdown the server stops accepting requests, and
+	// waits for in-flight requests to complete.
+	g.Go(func() error {
+		<-ctx.Done()
+		logrus.Infoln("server shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), spec.ShutdownTimeout)
+		defer cancel()
+		return server.Shutdown(ctx)
+	})
+
+	if err := g.Wait(); err != nil && err != context.Canceled {
 		logrus.Fatal(err)
 	}
+
+	// the secret leases and the vault token are revoked so
+	// that they cannot be used once the plugin stops. The
+	// static token is owned by the operator, and is only
+	// revoked if VAULT_TOKEN_REVOKE is enabled.
+	ctx, cancel := context.WithTimeout(context.Background(), spec.ShutdownTimeout)
+	defer cancel()
+	p.Revoke(ctx)
+	for _, renewer := range renewers {
+		renewer.Revoke(ctx)
+	}
+
+	logrus.Infoln("server stopped")
+}
+
+// helper function points the client at the first healthy
+// vault endpoint, and probes the endpoints in the background.
+func startFailover(ctx context.Context, g *errgroup.Group, client *api.Client, addrs []string, interval time.Duration) *failover.Failover {
+	logrus.Infof("vault failover enabled: %d endpoints", len(addrs))
+	if err := client.SetAddress(addrs[0]); err != nil {
+		logrus.Fatalln(err)
+	}
+	f := failover.New(client, addrs, interval)
+	f.Check(ctx)
+	g.Go(func() error {
+		return f.Run(ctx)
+	})
+	return f
+}
+
+// helper function returns the option that sends secret reads
+// for the named backend to the performance standby nodes.
+func standbyOption(backend, addr string) plugin.Option {
+	u, err := url.Parse(addr)
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+	logrus.Infof("vault standby reads enabled: %s", addr)
+	return plugin.WithStandby(backend, u)
 }
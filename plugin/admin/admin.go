@@ -0,0 +1,217 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/drone/drone-vault/plugin/cache"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// Cache provides cache inspection and invalidation.
+	Cache interface {
+		// List returns the cached entries.
+		List() map[Key]*cache.Entry
+
+		// Purge purges the cached entries that match the
+		// filter, and returns the purged keys.
+		Purge(filter Filter) []Key
+	}
+
+	// Key identifies a cached secret. An empty backend is
+	// the default vault backend, and an empty namespace is
+	// the root namespace.
+	Key struct {
+		Backend   string `json:"backend,omitempty"`
+		Namespace string `json:"namespace,omitempty"`
+		Path      string `json:"path"`
+	}
+
+	// Filter selects cached secrets by path, or by path
+	// prefix. If the backend or namespace is set, only
+	// secrets of the backend or namespace are selected.
+	Filter struct {
+		Backend   string
+		Namespace string
+		Path      string
+		Prefix    bool
+	}
+
+	// item represents a cached secret.
+	item struct {
+		Key
+		Created time.Time `json:"created"`
+		Expires time.Time `json:"expires"`
+		Age     int64     `json:"age"`
+		Expired bool      `json:"expired"`
+	}
+
+	// result represents the purge result.
+	result struct {
+		Purged []Key `json:"purged"`
+	}
+
+	handler struct {
+		tokens map[string]string
+		cache  Cache
+	}
+)
+
+// Handler returns an http.Handler that lists and purges
+// cached secrets. Requests are authenticated with a bearer
+// token, where tokens is a map of user names to tokens.
+func Handler(tokens map[string]string, cache Cache) http.Handler {
+	return &handler{
+		tokens: tokens,
+		cache:  cache,
+	}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.authenticate(r)
+	if !ok {
+		logrus.WithField("remote", r.RemoteAddr).
+			Warnln("admin: unauthorized request")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		h.list(w, r)
+	case http.MethodDelete:
+		h.purge(w, r, user)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// list writes the cached paths and their ages.
+func (h *handler) list(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	items := []*item{}
+	for key, entry := range h.cache.List() {
+		items = append(items, &item{
+			Key:     key,
+			Created: entry.Created,
+			Expires: entry.Expires,
+			Age:     int64(now.Sub(entry.Created) / time.Second),
+			Expired: entry.Expired(now),
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Key.less(items[j].Key)
+	})
+	writeJSON(w, items)
+}
+
+// purge purges the cached path, or all cached paths with
+// the prefix, optionally limited to a backend or namespace,
+// and records the purge in the audit log.
+func (h *handler) purge(w http.ResponseWriter, r *http.Request, user string) {
+	path := r.FormValue("path")
+	prefix := r.FormValue("prefix")
+	if (path == "") == (prefix == "") {
+		http.Error(w, "Either path or prefix is required", http.StatusBadRequest)
+		return
+	}
+	filter := Filter{
+		Backend:   r.FormValue("backend"),
+		Namespace: r.FormValue("namespace"),
+		Path:      path,
+	}
+	if prefix != "" {
+		filter.Path = prefix
+		filter.Prefix = true
+	}
+	purged := h.cache.Purge(filter)
+	logrus.WithFields(logrus.Fields{
+		"audit":     true,
+		"user":      user,
+		"remote":    r.RemoteAddr,
+		"backend":   filter.Backend,
+		"namespace": filter.Namespace,
+		"path":      path,
+		"prefix":    prefix,
+		"purged":    purged,
+	}).Infoln("admin: secret cache purged")
+	writeJSON(w, &result{Purged: purged})
+}
+
+// Match returns true if the filter selects the key.
+func (f Filter) Match(key Key) bool {
+	if f.Backend != "" && f.Backend != key.Backend {
+		return false
+	}
+	if f.Namespace != "" && strings.Trim(f.Namespace, "/") != strings.Trim(key.Namespace, "/") {
+		return false
+	}
+	if f.Prefix {
+		return strings.HasPrefix(key.Path, f.Path)
+	}
+	return key.Path == f.Path
+}
+
+// helper function orders keys by backend, namespace and
+// path.
+func (k Key) less(other Key) bool {
+	if k.Backend != other.Backend {
+		return k.Backend < other.Backend
+	}
+	if k.Namespace != other.Namespace {
+		return k.Namespace < other.Namespace
+	}
+	return k.Path < other.Path
+}
+
+// Protect returns an http.Handler that serves the handler
+// only to requests authenticated with an admin token.
+func Protect(tokens map[string]string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := authenticate(tokens, r); !ok {
+			logrus.WithField("remote", r.RemoteAddr).
+				Warnln("admin: unauthorized request")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// authenticate returns the user name for the bearer token
+// in the request authorization header.
+func (h *handler) authenticate(r *http.Request) (string, bool) {
+	return authenticate(h.tokens, r)
+}
+
+// helper function returns the user name for the bearer
+// token in the request authorization header.
+func authenticate(tokens map[string]string, r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	for user, want := range tokens {
+		if want == "" {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+			return user, true
+		}
+	}
+	return "", false
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package cache
+
+import "time"
+
+type (
+	// Entry represents a cached secret payload. An expired
+	// entry is retained by the cache until it is evicted,
+	// so that it can be served when vault is unavailable.
+	Entry struct {
+		Data    map[string]string `json:"data"`
+		Created time.Time         `json:"created"`
+		Expires time.Time         `json:"expires"`
+		Evicts  time.Time         `json:"evicts,omitempty"`
+	}
+
+	// Cache stores secret payloads keyed by Vault path.
+	Cache interface {
+		// Get returns the cached entry for the path. The
+		// entry may be expired, but is never evicted.
+		Get(path string) (*Entry, bool)
+
+		// Set stores the entry for the path.
+		Set(path string, entry *Entry)
+
+		// Delete removes the entry for the path.
+		Delete(path string)
+
+		// List returns all entries keyed by path.
+		List() map[string]*Entry
+	}
+)
+
+// Expired returns true if the entry is expired.
+func (e *Entry) Expired(now time.Time) bool {
+	return !now.Before(e.Expires)
+}
+
+// Evicted returns true if the entry should be removed
+// from the cache. If no eviction time is set, the entry
+// is evicted when it expires.
+func (e *Entry) Evicted(now time.Time) bool {
+	if e.Evicts.IsZero() {
+		return e.Expired(now)
+	}
+	return !now.Before(e.Evicts)
+}
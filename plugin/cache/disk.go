@@ -0,0 +1,269 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// file extension of cache entries.
+const ext = ".cache"
+
+type (
+	// disk is an encrypted, file-based cache that persists
+	// entries across restarts. Each entry is stored in a
+	// separate file named after the hash of the path.
+	disk struct {
+		sync.Mutex
+		dir  string
+		size int
+		aead cipher.AEAD
+	}
+
+	// record is the encrypted file payload.
+	record struct {
+		Path  string `json:"path"`
+		Entry *Entry `json:"entry"`
+	}
+)
+
+// NewDisk returns a new encrypted file-based cache that
+// stores entries in the given directory. The key is hashed
+// to derive the encryption key. If size is non-zero, the
+// oldest entries are removed once the cache exceeds size.
+func NewDisk(dir string, key []byte, size int) (Cache, error) {
+	if len(key) == 0 {
+		return nil, errors.New("cache: missing encryption key")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	c := &disk{
+		dir:  dir,
+		size: size,
+		aead: aead,
+	}
+	c.prune()
+	return c, nil
+}
+
+// Purge removes all entries from the cache directory.
+func Purge(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *disk) Get(path string) (*Entry, bool) {
+	c.Lock()
+	defer c.Unlock()
+	file := c.file(path)
+	rec, err := c.load(file)
+	if os.IsNotExist(err) {
+		return nil, false
+	}
+	if err != nil || rec.Path != path {
+		logrus.WithError(err).
+			WithField("file", file).
+			Warnln("cache: cannot read entry, removing")
+		os.Remove(file)
+		return nil, false
+	}
+	if rec.Entry.Evicted(time.Now()) {
+		os.Remove(file)
+		return nil, false
+	}
+	return rec.Entry, true
+}
+
+func (c *disk) Set(path string, entry *Entry) {
+	c.Lock()
+	defer c.Unlock()
+	err := c.store(c.file(path), &record{
+		Path:  path,
+		Entry: entry,
+	})
+	if err != nil {
+		logrus.WithError(err).
+			Warnln("cache: cannot write entry")
+		return
+	}
+	c.shrink()
+}
+
+func (c *disk) Delete(path string) {
+	c.Lock()
+	os.Remove(c.file(path))
+	c.Unlock()
+}
+
+func (c *disk) List() map[string]*Entry {
+	c.Lock()
+	defer c.Unlock()
+	now := time.Now()
+	entries := map[string]*Entry{}
+	for _, file := range c.files() {
+		rec, err := c.load(file)
+		if err != nil || rec.Entry.Evicted(now) {
+			os.Remove(file)
+			continue
+		}
+		entries[rec.Path] = rec.Entry
+	}
+	return entries
+}
+
+// helper function returns the file name for the path.
+func (c *disk) file(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+ext)
+}
+
+// helper function reads and decrypts the record from
+// the named file.
+func (c *disk) load(file string) (*record, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	n := c.aead.NonceSize()
+	if len(b) < n {
+		return nil, errors.New("cache: malformed entry")
+	}
+	b, err = c.aead.Open(nil, b[:n], b[n:], nil)
+	if err != nil {
+		return nil, err
+	}
+	rec := new(record)
+	if err := json.Unmarshal(b, rec); err != nil {
+		return nil, err
+	}
+	if rec.Entry == nil {
+		return nil, errors.New("cache: malformed entry")
+	}
+	return rec, nil
+}
+
+// helper function encrypts and writes the record to the
+// named file. The file is written to a temporary location
+// and renamed, so a partially written entry is never read.
+func (c *disk) store(file string, rec *record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	b = c.aead.Seal(nonce, nonce, b, nil)
+
+	tmp, err := ioutil.TempFile(c.dir, "tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// helper function removes evicted entries, and entries that
+// cannot be decrypted (for example, if the key changed).
+func (c *disk) prune() {
+	c.Lock()
+	defer c.Unlock()
+	now := time.Now()
+	for _, file := range c.files() {
+		rec, err := c.load(file)
+		if err != nil || rec.Entry.Evicted(now) {
+			os.Remove(file)
+		}
+	}
+}
+
+// helper function removes the least recently written entries
+// when the cache exceeds its size bound.
+func (c *disk) shrink() {
+	if c.size == 0 {
+		return
+	}
+	files := c.files()
+	if len(files) <= c.size {
+		return
+	}
+	type item struct {
+		name string
+		time time.Time
+	}
+	var items []item
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		items = append(items, item{file, info.ModTime()})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].time.Before(items[j].time)
+	})
+	for i := 0; i < len(items)-c.size; i++ {
+		os.Remove(items[i].name)
+	}
+}
+
+// helper function returns the cache entry files.
+func (c *disk) files() []string {
+	infos, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ext) {
+			continue
+		}
+		files = append(files, filepath.Join(c.dir, info.Name()))
+	}
+	return files
+}
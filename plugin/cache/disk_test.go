@@ -0,0 +1,129 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var key = []byte("bea26a2221fd8090ea38720fc445eca6")
+
+func TestDisk(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewDisk(dir, key, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	c.Set("secret/docker", &Entry{
+		Data:    map[string]string{"username": "david"},
+		Created: time.Now(),
+		Expires: time.Now().Add(time.Hour),
+	})
+
+	// the entry must survive a restart.
+	c, err = NewDisk(dir, key, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	entry, ok := c.Get("secret/docker")
+	if !ok {
+		t.Errorf("Want cached entry")
+		return
+	}
+	if got, want := entry.Data["username"], "david"; got != want {
+		t.Errorf("Want username %q, got %q", want, got)
+	}
+
+	// the entry must be encrypted at rest.
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+ext))
+	if len(files) != 1 {
+		t.Errorf("Want 1 cache file, got %d", len(files))
+		return
+	}
+	raw, _ := ioutil.ReadFile(files[0])
+	if bytes.Contains(raw, []byte("david")) {
+		t.Errorf("Want entry encrypted at rest")
+	}
+
+	if _, ok := c.List()["secret/docker"]; !ok {
+		t.Errorf("Want entry listed by path")
+	}
+
+	c.Delete("secret/docker")
+	if _, ok := c.Get("secret/docker"); ok {
+		t.Errorf("Want entry deleted")
+	}
+}
+
+func TestDisk_KeyChanged(t *testing.T) {
+	dir := t.TempDir()
+	c, _ := NewDisk(dir, key, 0)
+	c.Set("secret/docker", &Entry{
+		Data:    map[string]string{"username": "david"},
+		Created: time.Now(),
+		Expires: time.Now().Add(time.Hour),
+	})
+
+	c, _ = NewDisk(dir, []byte("correct-horse-battery-staple"), 0)
+	if _, ok := c.Get("secret/docker"); ok {
+		t.Errorf("Want entry unreadable with a different key")
+	}
+}
+
+func TestDisk_Expired(t *testing.T) {
+	c, _ := NewDisk(t.TempDir(), key, 0)
+	c.Set("secret/docker", &Entry{
+		Data:    map[string]string{"username": "david"},
+		Created: time.Now().Add(-time.Hour),
+		Expires: time.Now().Add(-time.Minute),
+	})
+	if _, ok := c.Get("secret/docker"); ok {
+		t.Errorf("Want expired entry evicted")
+	}
+}
+
+func TestDisk_Size(t *testing.T) {
+	c, _ := NewDisk(t.TempDir(), key, 2)
+	for i, path := range []string{"secret/a", "secret/b", "secret/c"} {
+		c.Set(path, &Entry{
+			Data:    map[string]string{"value": path},
+			Created: time.Now(),
+			Expires: time.Now().Add(time.Hour),
+		})
+		// ensure distinct modification times.
+		mod := time.Now().Add(time.Duration(i-3) * time.Hour)
+		os.Chtimes(c.(*disk).file(path), mod, mod)
+	}
+	if _, ok := c.Get("secret/a"); ok {
+		t.Errorf("Want oldest entry removed")
+	}
+	if _, ok := c.Get("secret/c"); !ok {
+		t.Errorf("Want newest entry retained")
+	}
+}
+
+func TestPurge(t *testing.T) {
+	dir := t.TempDir()
+	c, _ := NewDisk(dir, key, 0)
+	c.Set("secret/docker", &Entry{
+		Data:    map[string]string{"username": "david"},
+		Created: time.Now(),
+		Expires: time.Now().Add(time.Hour),
+	})
+	if err := Purge(dir); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, ok := c.Get("secret/docker"); ok {
+		t.Errorf("Want entry purged")
+	}
+}
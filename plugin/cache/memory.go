@@ -0,0 +1,64 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// memory is an in-memory cache.
+type memory struct {
+	sync.Mutex
+	entries map[string]*Entry
+}
+
+// NewMemory returns a new in-memory cache.
+func NewMemory() Cache {
+	return &memory{
+		entries: map[string]*Entry{},
+	}
+}
+
+func (c *memory) Get(path string) (*Entry, bool) {
+	c.Lock()
+	defer c.Unlock()
+	entry, ok := c.entries[path]
+	if !ok {
+		return nil, false
+	}
+	if entry.Evicted(time.Now()) {
+		delete(c.entries, path)
+		return nil, false
+	}
+	return entry, true
+}
+
+func (c *memory) Set(path string, entry *Entry) {
+	c.Lock()
+	c.entries[path] = entry
+	c.Unlock()
+}
+
+func (c *memory) Delete(path string) {
+	c.Lock()
+	delete(c.entries, path)
+	c.Unlock()
+}
+
+func (c *memory) List() map[string]*Entry {
+	c.Lock()
+	defer c.Unlock()
+	now := time.Now()
+	entries := map[string]*Entry{}
+	for path, entry := range c.entries {
+		if entry.Evicted(now) {
+			delete(c.entries, path)
+			continue
+		}
+		entries[path] = entry
+	}
+	return entries
+}
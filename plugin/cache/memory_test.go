@@ -0,0 +1,67 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	c := NewMemory()
+	c.Set("secret/docker", &Entry{
+		Data:    map[string]string{"username": "david"},
+		Created: time.Now(),
+		Expires: time.Now().Add(time.Hour),
+	})
+
+	entry, ok := c.Get("secret/docker")
+	if !ok {
+		t.Errorf("Want cached entry")
+		return
+	}
+	if got, want := entry.Data["username"], "david"; got != want {
+		t.Errorf("Want username %q, got %q", want, got)
+	}
+
+	if got := len(c.List()); got != 1 {
+		t.Errorf("Want 1 listed entry, got %d", got)
+	}
+
+	c.Delete("secret/docker")
+	if _, ok := c.Get("secret/docker"); ok {
+		t.Errorf("Want entry deleted")
+	}
+}
+
+func TestMemory_Expired(t *testing.T) {
+	c := NewMemory()
+	c.Set("secret/docker", &Entry{
+		Data:    map[string]string{"username": "david"},
+		Created: time.Now().Add(-time.Hour),
+		Expires: time.Now().Add(-time.Minute),
+	})
+	if _, ok := c.Get("secret/docker"); ok {
+		t.Errorf("Want expired entry evicted")
+	}
+}
+
+func TestMemory_Stale(t *testing.T) {
+	c := NewMemory()
+	c.Set("secret/docker", &Entry{
+		Data:    map[string]string{"username": "david"},
+		Created: time.Now().Add(-time.Hour),
+		Expires: time.Now().Add(-time.Minute),
+		Evicts:  time.Now().Add(time.Hour),
+	})
+	entry, ok := c.Get("secret/docker")
+	if !ok {
+		t.Errorf("Want expired entry retained until evicted")
+		return
+	}
+	if !entry.Expired(time.Now()) {
+		t.Errorf("Want entry expired")
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/drone/drone-vault/plugin/health"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// default interval between health probes.
+	defaultInterval = 10 * time.Second
+
+	// timeout of a single health probe.
+	probeTimeout = 5 * time.Second
+)
+
+var (
+	// ErrSealed is returned when the vault node is sealed.
+	ErrSealed = errors.New("vault: node is sealed")
+
+	// ErrUninitialized is returned when the vault node is
+	// not initialized.
+	ErrUninitialized = errors.New("vault: node is not initialized")
+
+	// ErrNoEndpoint is returned when no vault endpoint is
+	// healthy.
+	ErrNoEndpoint = errors.New("vault: no healthy endpoint")
+)
+
+// Failover probes an ordered list of vault addresses with
+// the sys/health api, and points the client at the first
+// healthy address. The client returns to the primary
+// address, the first in the list, once it is healthy again.
+//
+// The addresses must belong to the same vault cluster. The
+// client address is changed in place, so the client token,
+// and any auth method or renewer that uses the client, is
+// shared by all addresses.
+type Failover struct {
+	client   *api.Client
+	addrs    []string
+	interval time.Duration
+
+	mu      sync.Mutex
+	active  string
+	err     error
+	errs    map[string]error
+	updated time.Time
+}
+
+// New returns a new Failover for the client.
+func New(client *api.Client, addrs []string, interval time.Duration) *Failover {
+	if interval == 0 {
+		interval = defaultInterval
+	}
+	return &Failover{
+		client:   client,
+		addrs:    addrs,
+		interval: interval,
+		active:   client.Address(),
+	}
+}
+
+// Check probes each address in order, and points the client
+// at the first healthy address. If no address is healthy,
+// the client address is unchanged.
+func (f *Failover) Check(ctx context.Context) error {
+	errs := map[string]error{}
+	next := ""
+	for _, addr := range f.addrs {
+		err := f.probe(ctx, addr)
+		errs[addr] = err
+		if err != nil {
+			logrus.WithError(err).
+				WithField("address", addr).
+				Debugln("vault: endpoint unhealthy")
+			continue
+		}
+		next = addr
+		break
+	}
+
+	f.mu.Lock()
+	prev := f.active
+	f.errs = errs
+	f.err = nil
+	f.updated = time.Now()
+	if next != "" {
+		f.active = next
+	} else {
+		f.err = ErrNoEndpoint
+	}
+	f.mu.Unlock()
+
+	if next == "" {
+		logrus.WithField("address", prev).
+			Errorln("vault: no healthy endpoint")
+		return ErrNoEndpoint
+	}
+	if next != prev {
+		if err := f.client.SetAddress(next); err != nil {
+			return err
+		}
+		logrus.WithField("address", next).
+			WithField("previous", prev).
+			Warnln("vault: switched endpoint")
+	}
+	return nil
+}
+
+// Run probes the addresses at scheduled intervals.
+func (f *Failover) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(f.interval):
+			f.Check(ctx)
+		}
+	}
+}
+
+// Health returns the result of the last health probe.
+func (f *Failover) Health() health.Status {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status := health.Status{
+		Name:    "vault",
+		Healthy: f.err == nil,
+		Updated: f.updated,
+		Details: map[string]interface{}{
+			"address": f.active,
+		},
+	}
+	endpoints := map[string]string{}
+	for addr, err := range f.errs {
+		if err != nil {
+			endpoints[addr] = err.Error()
+		} else {
+			endpoints[addr] = "ok"
+		}
+	}
+	if len(endpoints) != 0 {
+		status.Details["endpoints"] = endpoints
+	}
+	if f.err != nil {
+		status.Error = f.err.Error()
+	}
+	return status
+}
+
+// helper function probes the vault address with the
+// sys/health api. Standby nodes are healthy, since they
+// forward requests to the active node.
+func (f *Failover) probe(ctx context.Context, addr string) error {
+	client, err := f.client.Clone()
+	if err != nil {
+		return err
+	}
+	if err := client.SetAddress(addr); err != nil {
+		return err
+	}
+	client.SetMaxRetries(0)
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	resp, err := client.Sys().HealthWithContext(ctx)
+	switch {
+	case err != nil:
+		return err
+	case !resp.Initialized:
+		return ErrUninitialized
+	case resp.Sealed:
+		return ErrSealed
+	}
+	return nil
+}
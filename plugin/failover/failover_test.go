@@ -0,0 +1,103 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package failover
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+var noContext = context.Background()
+
+// helper function returns a fake vault node that reports
+// its health from the named testdata file.
+func node(name *atomic.Value) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out, _ := ioutil.ReadFile(name.Load().(string))
+		w.Write(out)
+	}))
+}
+
+func TestFailover(t *testing.T) {
+	primaryHealth := new(atomic.Value)
+	primaryHealth.Store("testdata/sealed.json")
+	primary := node(primaryHealth)
+	defer primary.Close()
+
+	secondaryHealth := new(atomic.Value)
+	secondaryHealth.Store("testdata/health.json")
+	secondary := node(secondaryHealth)
+	defer secondary.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: primary.URL})
+	client.SetToken("8609694a-cdbc-db9b-d345-e782dbb562ed")
+
+	f := New(client, []string{primary.URL, secondary.URL}, time.Minute)
+
+	// the primary is sealed, and the client fails over to
+	// the secondary.
+	if err := f.Check(noContext); err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := client.Address(), secondary.URL; got != want {
+		t.Errorf("Want address %s, got %s", want, got)
+	}
+	if status := f.Health(); !status.Healthy || status.Details["address"] != secondary.URL {
+		t.Errorf("Want healthy status, got %+v", status)
+	}
+
+	// the primary is unsealed, and the client returns to
+	// the primary.
+	primaryHealth.Store("testdata/health.json")
+	if err := f.Check(noContext); err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := client.Address(), primary.URL; got != want {
+		t.Errorf("Want address %s, got %s", want, got)
+	}
+
+	// the token is shared by all endpoints.
+	if got, want := client.Token(), "8609694a-cdbc-db9b-d345-e782dbb562ed"; got != want {
+		t.Errorf("Want token unchanged, got %s", got)
+	}
+}
+
+func TestFailover_Unavailable(t *testing.T) {
+	primaryHealth := new(atomic.Value)
+	primaryHealth.Store("testdata/sealed.json")
+	primary := node(primaryHealth)
+	defer primary.Close()
+
+	// the secondary is unreachable.
+	secondary := httptest.NewServer(http.NotFoundHandler())
+	secondary.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: primary.URL})
+	f := New(client, []string{primary.URL, secondary.URL}, time.Minute)
+
+	if err := f.Check(noContext); err != ErrNoEndpoint {
+		t.Errorf("Want ErrNoEndpoint, got %v", err)
+	}
+	if got, want := client.Address(), primary.URL; got != want {
+		t.Errorf("Want address unchanged %s, got %s", want, got)
+	}
+	status := f.Health()
+	if status.Healthy {
+		t.Errorf("Want unhealthy status")
+	}
+	endpoints := status.Details["endpoints"].(map[string]string)
+	if got, want := endpoints[primary.URL], ErrSealed.Error(); got != want {
+		t.Errorf("Want primary error %q, got %q", want, got)
+	}
+}
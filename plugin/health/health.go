@@ -0,0 +1,93 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type (
+	// Status reports the health of a component.
+	Status struct {
+		Name     string                 `json:"name"`
+		Healthy  bool                   `json:"healthy"`
+		Error    string                 `json:"error,omitempty"`
+		Failures int                    `json:"failures,omitempty"`
+		Updated  time.Time              `json:"updated,omitempty"`
+		Details  map[string]interface{} `json:"details,omitempty"`
+	}
+
+	// Checker is implemented by components that report
+	// their health status.
+	Checker interface {
+		Health() Status
+	}
+)
+
+// Handler returns an http.Handler that writes the health
+// status of each component. The response status code is
+// 503 if any component is unhealthy. The handler is served
+// without authentication, so errors and details are omitted,
+// except for the token ttl.
+func Handler(checks ...Checker) http.Handler {
+	return handler(checks, summary)
+}
+
+// DetailHandler returns an http.Handler that writes the
+// health status of each component, including errors and
+// details such as the token policies. The handler should
+// only be served to authenticated users.
+func DetailHandler(checks ...Checker) http.Handler {
+	return handler(checks, func(s Status) Status { return s })
+}
+
+func handler(checks []Checker, view func(Status) Status) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := http.StatusOK
+		out := []Status{}
+		for _, check := range checks {
+			status := check.Health()
+			if !status.Healthy {
+				code = http.StatusServiceUnavailable
+			}
+			out = append(out, view(status))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(out)
+	})
+}
+
+// helper function returns the status without the error and
+// details, except for the token ttl.
+func summary(s Status) Status {
+	details := s.Details
+	s.Error = ""
+	s.Details = nil
+	if ttl, ok := details["ttl"]; ok {
+		s.Details = map[string]interface{}{"ttl": ttl}
+	}
+	return s
+}
+
+// Named returns a Checker that prefixes the component name
+// of the status, for example to report the token status of
+// each named vault backend.
+func Named(name string, check Checker) Checker {
+	return named{name: name, check: check}
+}
+
+type named struct {
+	name  string
+	check Checker
+}
+
+func (n named) Health() Status {
+	status := n.check.Health()
+	status.Name = n.name + "/" + status.Name
+	return status
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package health
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeChecker Status
+
+func (c fakeChecker) Health() Status { return Status(c) }
+
+func TestHandler(t *testing.T) {
+	h := Handler(fakeChecker{Name: "token", Healthy: true})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/healthz", nil)
+	h.ServeHTTP(w, r)
+	if got, want := w.Code, 200; got != want {
+		t.Errorf("Want status code %d, got %d", want, got)
+	}
+
+	out := []Status{}
+	json.NewDecoder(w.Body).Decode(&out)
+	if len(out) != 1 || out[0].Name != "token" || !out[0].Healthy {
+		t.Errorf("Want healthy token status, got %v", out)
+	}
+}
+
+func TestHandler_Unhealthy(t *testing.T) {
+	h := Handler(
+		fakeChecker{Name: "token", Healthy: true},
+		fakeChecker{Name: "vault", Healthy: false, Error: "connection refused"},
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/healthz", nil)
+	h.ServeHTTP(w, r)
+	if got, want := w.Code, 503; got != want {
+		t.Errorf("Want status code %d, got %d", want, got)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	check := Named("prod", fakeChecker{Name: "token", Healthy: true})
+	if got, want := check.Health().Name, "prod/token"; got != want {
+		t.Errorf("Want name %q, got %q", want, got)
+	}
+}
+
+// Test errors and details other than the ttl are omitted
+// from the unauthenticated health status.
+func TestHandler_Summary(t *testing.T) {
+	check := fakeChecker{
+		Name:    "token-check",
+		Healthy: false,
+		Error:   "permission denied",
+		Details: map[string]interface{}{
+			"policies": []string{"default"},
+			"ttl":      1800,
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/healthz", nil)
+	Handler(check).ServeHTTP(w, r)
+
+	out := []Status{}
+	json.NewDecoder(w.Body).Decode(&out)
+	if len(out) != 1 || out[0].Error != "" || len(out[0].Details) != 1 || out[0].Details["ttl"] != float64(1800) {
+		t.Errorf("Want summary status with ttl, got %+v", out)
+	}
+
+	w = httptest.NewRecorder()
+	DetailHandler(check).ServeHTTP(w, r)
+	out = []Status{}
+	json.NewDecoder(w.Body).Decode(&out)
+	if len(out) != 1 || out[0].Error == "" || out[0].Details["policies"] == nil {
+		t.Errorf("Want detailed status, got %+v", out)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Revoker revokes the secret leases issued to the plugin.
+type Revoker interface {
+	// Revoke revokes the leases of secrets read from vault
+	// that have not yet expired.
+	Revoke(ctx context.Context) error
+}
+
+// lease records a secret lease issued by vault.
+type lease struct {
+	loc     location
+	expires time.Time
+}
+
+// leases tracks the secret leases issued by vault, keyed
+// by lease id, so they can be revoked on shutdown.
+type leases struct {
+	sync.Mutex
+	entries map[string]lease
+}
+
+// add records the lease for the secret, and discards leases
+// that have expired.
+func (l *leases) add(id string, loc location, ttl time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	now := time.Now()
+	if l.entries == nil {
+		l.entries = map[string]lease{}
+	}
+	for k, v := range l.entries {
+		if now.After(v.expires) {
+			delete(l.entries, k)
+		}
+	}
+	l.entries[id] = lease{loc: loc, expires: now.Add(ttl)}
+}
+
+// list returns the leases that have not expired.
+func (l *leases) list() map[string]lease {
+	l.Lock()
+	defer l.Unlock()
+	now := time.Now()
+	out := map[string]lease{}
+	for k, v := range l.entries {
+		if now.Before(v.expires) {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// remove discards the lease.
+func (l *leases) remove(id string) {
+	l.Lock()
+	delete(l.entries, id)
+	l.Unlock()
+}
+
+// Revoke revokes the leases of secrets read from vault that
+// have not yet expired. The cached payload of each revoked
+// secret is discarded, since it is no longer valid.
+func (p *plugin) Revoke(ctx context.Context) error {
+	var last error
+	for id, v := range p.leases.list() {
+		err := p.clientFor(v.loc).Sys().RevokeWithContext(ctx, id)
+		if err != nil {
+			logrus.WithError(err).
+				WithField("secret", v.loc.key()).
+				Warnln("vault: cannot revoke secret lease")
+			last = err
+			continue
+		}
+		p.leases.remove(id)
+		if p.cache != nil {
+			p.cache.Delete(v.loc.key())
+		}
+		logrus.WithField("secret", v.loc.key()).
+			Debugln("vault: secret lease revoked")
+	}
+	return last
+}
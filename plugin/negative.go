@@ -0,0 +1,108 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"sync"
+	"time"
+)
+
+// negative caches secret not found and secret key not found
+// results, keyed by path, so that misconfigured pipelines do
+// not repeatedly read non-existent secrets from vault.
+type negative struct {
+	sync.Mutex
+	ttl     time.Duration
+	entries map[string]map[string]time.Time
+	swept   time.Time
+}
+
+func newNegative(ttl time.Duration) *negative {
+	return &negative{
+		ttl:     ttl,
+		entries: map[string]map[string]time.Time{},
+	}
+}
+
+// find returns the cached not found error for the path and
+// key name, if any.
+func (n *negative) find(path, name string) error {
+	n.Lock()
+	defer n.Unlock()
+	keys, ok := n.entries[path]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	if expires, ok := keys[""]; ok && now.Before(expires) {
+		return errNotFound
+	}
+	if expires, ok := keys[name]; ok && now.Before(expires) {
+		return errKeyNotFound
+	}
+	return nil
+}
+
+// set caches a not found result for the path. If the name
+// is empty, the secret was not found, else the secret key
+// was not found.
+func (n *negative) set(path, name string) {
+	n.Lock()
+	defer n.Unlock()
+	keys, ok := n.entries[path]
+	if !ok {
+		keys = map[string]time.Time{}
+		n.entries[path] = keys
+	}
+	now := time.Now()
+	keys[name] = now.Add(n.ttl)
+
+	// opportunistically remove expired entries for the
+	// path so that the map does not grow unbounded.
+	for k, expires := range keys {
+		if !now.Before(expires) {
+			delete(keys, k)
+		}
+	}
+
+	// expired entries for other paths are removed at most
+	// once per ttl, since a misconfigured pipeline may read
+	// many different paths that do not exist.
+	if now.Sub(n.swept) >= n.ttl {
+		n.swept = now
+		for path, keys := range n.entries {
+			for k, expires := range keys {
+				if !now.Before(expires) {
+					delete(keys, k)
+				}
+			}
+			if len(keys) == 0 {
+				delete(n.entries, path)
+			}
+		}
+	}
+}
+
+// remove removes all cached not found results for the path.
+func (n *negative) remove(path string) {
+	n.Lock()
+	delete(n.entries, path)
+	n.Unlock()
+}
+
+// purge removes all cached not found results for the paths
+// that match, and returns the purged paths.
+func (n *negative) purge(match func(path string) bool) []string {
+	n.Lock()
+	defer n.Unlock()
+	var purged []string
+	for key := range n.entries {
+		if match(key) {
+			delete(n.entries, key)
+			purged = append(purged, key)
+		}
+	}
+	return purged
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNegative(t *testing.T) {
+	n := newNegative(time.Hour)
+	if err := n.find("secret/docker", "username"); err != nil {
+		t.Errorf("Want no cached result, got %v", err)
+	}
+
+	n.set("secret/docker", "token")
+	if err := n.find("secret/docker", "token"); err != errKeyNotFound {
+		t.Errorf("Want key not found, got %v", err)
+	}
+	if err := n.find("secret/docker", "username"); err != nil {
+		t.Errorf("Want no cached result, got %v", err)
+	}
+
+	n.set("secret/docker", "")
+	if err := n.find("secret/docker", "username"); err != errNotFound {
+		t.Errorf("Want not found, got %v", err)
+	}
+
+	n.remove("secret/docker")
+	if err := n.find("secret/docker", "token"); err != nil {
+		t.Errorf("Want no cached result, got %v", err)
+	}
+}
+
+func TestNegative_Expired(t *testing.T) {
+	n := newNegative(-time.Second)
+	n.set("secret/docker", "")
+	if err := n.find("secret/docker", "username"); err != nil {
+		t.Errorf("Want expired result ignored, got %v", err)
+	}
+}
+
+func TestNegative_PurgePrefix(t *testing.T) {
+	n := newNegative(time.Hour)
+	n.set("secret/docker", "")
+	n.set("secret/npm", "token")
+	n.set("kv/docker", "")
+
+	purged := n.purge(func(path string) bool {
+		return strings.HasPrefix(path, "secret/")
+	})
+	if got := len(purged); got != 2 {
+		t.Errorf("Want 2 purged paths, got %d", got)
+	}
+	if err := n.find("kv/docker", "username"); err != errNotFound {
+		t.Errorf("Want not found, got %v", err)
+	}
+}
+
+func TestNegative_Sweep(t *testing.T) {
+	n := newNegative(time.Hour)
+	n.entries["secret/npm"] = map[string]time.Time{
+		"": time.Now().Add(-time.Second),
+	}
+	n.set("secret/docker", "")
+	if _, ok := n.entries["secret/npm"]; ok {
+		t.Errorf("Want expired path removed")
+	}
+	if _, ok := n.entries["secret/docker"]; !ok {
+		t.Errorf("Want path cached")
+	}
+}
@@ -7,28 +7,223 @@ package plugin
 import (
 	"context"
 	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/drone/drone-go/drone"
 	"github.com/drone/drone-go/plugin/secret"
+	"github.com/drone/drone-vault/plugin/admin"
+	"github.com/drone/drone-vault/plugin/cache"
+	"github.com/drone/drone-vault/plugin/health"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/hashicorp/vault/api"
 )
 
+// Plugin is a secret plugin that sources secrets from vault.
+// In addition to finding secrets, the plugin provides cache
+// administration, cache warming, lease revocation and health
+// reporting.
+type Plugin interface {
+	secret.Plugin
+	admin.Cache
+	health.Checker
+	Warmer
+	Revoker
+}
+
+// Option configures the secret plugin.
+type Option func(*plugin)
+
+// WithCache returns an option that caches secret payloads
+// by path. Entries expire after the lease duration returned
+// by Vault, capped at the given max age. A zero max age
+// expires entries immediately, which is only useful when
+// combined with WithStaleIfError.
+func WithCache(c cache.Cache, maxAge time.Duration) Option {
+	return func(p *plugin) {
+		p.cache = c
+		p.maxAge = maxAge
+	}
+}
+
+// WithStaleIfError returns an option that serves the last
+// successfully read payload for a path, up to the given
+// staleness bound, when vault cannot be reached. It has no
+// effect unless a cache is configured.
+func WithStaleIfError(stale time.Duration) Option {
+	return func(p *plugin) {
+		p.stale = stale
+	}
+}
+
+// WithNegativeCache returns an option that caches secret
+// not found and secret key not found results for the given
+// duration. Cached results for a path are discarded as soon
+// as the path is successfully read from vault.
+func WithNegativeCache(ttl time.Duration) Option {
+	return func(p *plugin) {
+		p.negative = newNegative(ttl)
+	}
+}
+
+// WithNamespaces returns an option that maps repository
+// namespaces, such as the Drone organization, to the vault
+// namespace from which the repository secrets are read.
+// Secrets for unmapped repositories, and secrets read from
+// a named backend, are read from the vault namespace of the
+// client.
+func WithNamespaces(namespaces map[string]string) Option {
+	return func(p *plugin) {
+		p.namespaces = namespaces
+	}
+}
+
+// WithBackend returns an option that adds a named vault
+// backend. Secrets are read from the backend when the path
+// is prefixed with the backend name, for example
+// prod:secret/docker, or matches a backend path prefix.
+func WithBackend(name string, client *api.Client) Option {
+	return func(p *plugin) {
+		if p.backends == nil {
+			p.backends = map[string]*api.Client{}
+		}
+		p.backends[name] = client
+	}
+}
+
+// WithBackendPrefixes returns an option that maps secret
+// path prefixes to named vault backends. The longest
+// matching prefix is used.
+func WithBackendPrefixes(prefixes map[string]string) Option {
+	return func(p *plugin) {
+		p.prefixes = prefixes
+	}
+}
+
+// WithStandby returns an option that sends secret reads for
+// the named vault backend to the performance standby nodes
+// at the given address. An empty name is the default vault
+// backend. The client should enable read-your-writes, so
+// that a standby that has not caught up with the client
+// replication state forwards the read to the active node.
+func WithStandby(backend string, addr *url.URL) Option {
+	return func(p *plugin) {
+		if p.standby == nil {
+			p.standby = map[string]*url.URL{}
+		}
+		p.standby[backend] = addr
+	}
+}
+
+// WithActivePrefixes returns an option that forces reads of
+// secret paths with the given prefixes to the active node,
+// for example secrets that are rotated by an external job
+// and must be read as soon as they are written.
+func WithActivePrefixes(prefixes []string) Option {
+	return func(p *plugin) {
+		p.active = prefixes
+	}
+}
+
 // New returns a new secret plugin that sources secrets
 // from the AWS secrets manager.
-func New(client *api.Client, disallowForks bool) secret.Plugin {
-	return &plugin{
+func New(client *api.Client, disallowForks bool, opts ...Option) Plugin {
+	p := &plugin{
 		client:        client,
 		disallowForks: disallowForks,
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 type plugin struct {
 	client        *api.Client
 	disallowForks bool
+	namespaces    map[string]string
+	backends      map[string]*api.Client
+	prefixes      map[string]string
+	standby       map[string]*url.URL
+	active        []string
+
+	cache  cache.Cache
+	maxAge time.Duration
+	stale  time.Duration
+	group  singleflight.Group
+
+	negative *negative
+	leases   leases
+
+	// number of stale payloads served.
+	staleCount uint64
 }
 
+// location identifies a secret path in a vault namespace
+// of a named vault backend. An empty backend name is the
+// default vault backend.
+type location struct {
+	backend   string
+	namespace string
+	path      string
+}
+
+// separates the backend, namespace and path in the cache
+// key. The separator cannot occur in a secret path, so a
+// path cannot be crafted to match the key of a secret in
+// another namespace or backend.
+const keySeparator = "\x00"
+
+// key returns the key used to cache the secret. The key is
+// the path if the secret is read from the root namespace of
+// the default backend, otherwise the path is qualified with
+// the backend name and the namespace.
+func (l location) key() string {
+	namespace := strings.Trim(l.namespace, "/")
+	if l.backend == "" && namespace == "" {
+		return l.path
+	}
+	return l.backend + keySeparator + namespace + keySeparator + l.path
+}
+
+// parseKey returns the location of the cached secret. A key
+// without a separator is a path in the root namespace of the
+// default backend.
+func parseKey(key string) location {
+	parts := strings.SplitN(key, keySeparator, 3)
+	if len(parts) != 3 {
+		return location{path: key}
+	}
+	return location{
+		backend:   parts[0],
+		namespace: parts[1],
+		path:      parts[2],
+	}
+}
+
+// adminKey returns the key reported by the cache admin api.
+func (l location) adminKey() admin.Key {
+	return admin.Key{
+		Backend:   l.backend,
+		Namespace: strings.Trim(l.namespace, "/"),
+		Path:      l.path,
+	}
+}
+
+var (
+	// errNotFound is returned when the secret does not exist.
+	errNotFound = errors.New("secret not found")
+
+	// errKeyNotFound is returned when the secret key does
+	// not exist.
+	errKeyNotFound = errors.New("secret key not found")
+)
+
 func (p *plugin) Find(ctx context.Context, req *secret.Request) (*drone.Secret, error) {
 	// The Fork attribute will be empty on a branch build (e.g. master).
 	// Branch builds cannot be from a fork.
@@ -46,21 +241,48 @@ func (p *plugin) Find(ctx context.Context, req *secret.Request) (*drone.Secret,
 		"fork":   forkRepo,
 	})
 
-	path := req.Path
+	// the separator is reserved for cache keys, and is not
+	// valid in a vault path.
+	if strings.Contains(req.Path, keySeparator) {
+		return nil, errNotFound
+	}
+
+	loc := p.locate(req.Path, req.Repo.Namespace)
 	name := req.Name
 	if name == "" {
 		name = "value"
 	}
+	if loc.namespace != "" {
+		logEvent = logEvent.WithField("namespace", loc.namespace)
+	}
+	if loc.backend != "" {
+		logEvent = logEvent.WithField("backend", loc.backend)
+	}
 
-	// makes an api call to the aws secrets manager and attempts
-	// to retrieve the secret at the requested path.
-	params, err := p.find(path)
+	// checks whether the secret, or secret key, was recently
+	// not found and returns early to avoid a vault request.
+	if p.negative != nil {
+		if err := p.negative.find(loc.key(), name); err != nil {
+			logEvent.Debug("secret not found (cached)")
+			return nil, err
+		}
+	}
+
+	// makes an api call to vault, or checks the cache, and
+	// attempts to retrieve the secret at the requested path.
+	// access filters are always evaluated below, against the
+	// cached payload, so that a cached secret is never served
+	// to an unauthorized repository.
+	params, err := p.find(loc)
 	if err != nil {
-		return nil, errors.New("secret not found")
+		return nil, errNotFound
 	}
 	value, ok := params[name]
 	if !ok {
-		return nil, errors.New("secret key not found")
+		if p.negative != nil {
+			p.negative.set(loc.key(), name)
+		}
+		return nil, errKeyNotFound
 	}
 
 	// the user can filter out requests based on event type
@@ -116,14 +338,235 @@ func (p *plugin) Find(ctx context.Context, req *secret.Request) (*drone.Secret,
 	}, nil
 }
 
-// helper function returns the secret from vault.
-func (p *plugin) find(path string) (map[string]string, error) {
-	secret, err := p.client.Logical().Read(path)
+// Health returns the status of the secret cache, including
+// the number of stale payloads served.
+func (p *plugin) Health() health.Status {
+	return health.Status{
+		Name:    "cache",
+		Healthy: true,
+		Details: map[string]interface{}{
+			"stale": atomic.LoadUint64(&p.staleCount),
+		},
+	}
+}
+
+// List returns the cached secret payloads.
+func (p *plugin) List() map[admin.Key]*cache.Entry {
+	entries := map[admin.Key]*cache.Entry{}
+	if p.cache == nil {
+		return entries
+	}
+	for key, entry := range p.cache.List() {
+		entries[parseKey(key).adminKey()] = entry
+	}
+	return entries
+}
+
+// Purge removes the cached secret payloads and cached not
+// found results that match the filter, and returns the
+// purged keys.
+func (p *plugin) Purge(filter admin.Filter) []admin.Key {
+	match := func(key string) bool {
+		return filter.Match(parseKey(key).adminKey())
+	}
+	seen := map[string]struct{}{}
+	if p.cache != nil {
+		for key := range p.cache.List() {
+			if match(key) {
+				p.cache.Delete(key)
+				seen[key] = struct{}{}
+			}
+		}
+	}
+	if p.negative != nil {
+		for _, key := range p.negative.purge(match) {
+			seen[key] = struct{}{}
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	purged := make([]admin.Key, 0, len(keys))
+	for _, key := range keys {
+		purged = append(purged, parseKey(key).adminKey())
+	}
+	return purged
+}
+
+// helper function returns the secret from the cache or,
+// on a cache miss, from vault.
+func (p *plugin) find(loc location) (map[string]string, error) {
+	if p.cache == nil {
+		params, _, err := p.fetch(loc)
+		return params, err
+	}
+	key := loc.key()
+	entry, ok := p.cache.Get(key)
+	if ok && !entry.Expired(time.Now()) {
+		logrus.WithField("secret", key).
+			Traceln("vault: secret cache hit")
+		return entry.Data, nil
+	}
+	fresh, err := p.refresh(loc)
+	// if vault is sealed or unreachable the last payload
+	// successfully read from vault is served. other errors,
+	// for example permission denied, are never served stale,
+	// so that revoked access takes effect immediately.
+	if err != nil && unavailable(err) && ok && p.stale > 0 {
+		count := atomic.AddUint64(&p.staleCount, 1)
+		logrus.WithError(err).
+			WithField("secret", key).
+			WithField("age", time.Since(entry.Created)).
+			WithField("stale_count", count).
+			Warnln("vault: cannot read secret, serving stale secret")
+		return entry.Data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fresh.Data, nil
+}
+
+// helper function returns true if the error indicates vault
+// is sealed or unreachable, as opposed to an error returned
+// by vault for the request, such as permission denied.
+func unavailable(err error) bool {
+	if err == errNotFound || err == errKeyNotFound {
+		return false
+	}
+	var resp *api.ResponseError
+	if errors.As(err, &resp) {
+		return resp.StatusCode >= 500
+	}
+	return true
+}
+
+// helper function reads the secret from vault and stores
+// the secret in the cache. concurrent reads of the same
+// path are collapsed into a single vault request.
+func (p *plugin) refresh(loc location) (*cache.Entry, error) {
+	key := loc.key()
+	v, err, _ := p.group.Do(key, func() (interface{}, error) {
+		params, lease, err := p.fetch(loc)
+		if err == errNotFound {
+			p.cache.Delete(key)
+		}
+		if err != nil {
+			return nil, err
+		}
+		ttl := p.maxAge
+		if lease > 0 && lease < ttl {
+			ttl = lease
+		}
+		now := time.Now()
+		entry := &cache.Entry{
+			Data:    params,
+			Created: now,
+			Expires: now.Add(ttl),
+			Evicts:  now.Add(ttl + p.stale),
+		}
+		p.cache.Set(key, entry)
+		return entry, nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return v.(*cache.Entry), nil
+}
+
+// helper function returns the secret from vault and records
+// the result in the negative cache.
+func (p *plugin) fetch(loc location) (map[string]string, time.Duration, error) {
+	params, lease, err := p.read(loc)
+	if p.negative != nil {
+		switch err {
+		case nil:
+			p.negative.remove(loc.key())
+		case errNotFound:
+			p.negative.set(loc.key(), "")
+		}
+	}
+	return params, lease, err
+}
+
+// helper function returns the location of the secret path
+// for the repository namespace. the secret is read from the
+// vault namespace mapped to the repository namespace or,
+// if unmapped, from the namespace of the vault client, and
+// the location always records the namespace the read runs
+// in, so that the cache key identifies the secret. the
+// mapping only applies to the default backend, since the
+// namespaces of one cluster do not exist in another.
+func (p *plugin) locate(path, namespace string) location {
+	loc := p.route(path)
+	if loc.backend == "" {
+		loc.namespace = p.namespaces[namespace]
+	}
+	if loc.namespace == "" {
+		loc.namespace = p.clientFor(loc).Namespace()
+	}
+	return loc
+}
+
+// helper function returns the location of the secret path.
+// the path is routed to a named vault backend if it is
+// prefixed with the backend name, or if it matches a
+// backend path prefix.
+func (p *plugin) route(path string) location {
+	if i := strings.Index(path, ":"); i > 0 {
+		if _, ok := p.backends[path[:i]]; ok {
+			return location{backend: path[:i], path: path[i+1:]}
+		}
+	}
+	var match string
+	for prefix, name := range p.prefixes {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(match) {
+			if _, ok := p.backends[name]; ok {
+				match = prefix
+			}
+		}
+	}
+	if match != "" {
+		return location{backend: p.prefixes[match], path: path}
+	}
+	return location{path: path}
+}
+
+// helper function returns the vault client for the secret
+// location. the client is copied, rather than modified, so
+// that reads in different namespaces can run concurrently.
+func (p *plugin) clientFor(loc location) *api.Client {
+	client := p.client
+	if loc.backend != "" {
+		client = p.backends[loc.backend]
+	}
+	if loc.namespace == "" {
+		return client
+	}
+	return client.WithNamespace(loc.namespace)
+}
+
+// helper function returns the secret from vault, along
+// with the secret lease duration.
+func (p *plugin) read(loc location) (map[string]string, time.Duration, error) {
+	secret, err := p.readerFor(loc).Logical().Read(loc.path)
+	// if the standby nodes are unreachable or unhealthy the
+	// secret is read from the client address, which routes
+	// the read to the active node.
+	if err != nil && unavailable(err) && p.fromStandby(loc) {
+		logrus.WithError(err).
+			WithField("secret", loc.path).
+			WithField("backend", loc.backend).
+			Warnln("vault: cannot read secret from standby, reading from client address")
+		secret, err = p.clientFor(loc).Logical().Read(loc.path)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
 	if secret == nil || secret.Data == nil {
-		return nil, errors.New("secret not found")
+		return nil, 0, errNotFound
 	}
 
 	// HACK: the vault v2 key value store is confusing
@@ -142,5 +585,12 @@ func (p *plugin) find(path string) (map[string]string, error) {
 		}
 		params[k] = s
 	}
-	return params, err
+	lease := time.Duration(secret.LeaseDuration) * time.Second
+
+	// dynamic secrets are issued with a lease, which is
+	// recorded so that it can be revoked on shutdown.
+	if secret.LeaseID != "" {
+		p.leases.add(secret.LeaseID, loc, lease)
+	}
+	return params, lease, err
 }
@@ -0,0 +1,57 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// helper function returns the vault client used to read the
+// secret. reads are sent to the performance standby nodes,
+// if configured, unless the path must be read from the
+// active node.
+func (p *plugin) readerFor(loc location) *api.Client {
+	client := p.clientFor(loc)
+	if p.forceActive(loc.path) {
+		return client.WithRequestCallbacks(api.ForwardAlways())
+	}
+	if addr, ok := p.standby[loc.backend]; ok {
+		return client.WithRequestCallbacks(
+			withAddress(addr),
+			api.ForwardInconsistent(),
+		)
+	}
+	return client
+}
+
+// helper function returns true if the secret is read from
+// the performance standby nodes.
+func (p *plugin) fromStandby(loc location) bool {
+	_, ok := p.standby[loc.backend]
+	return ok && !p.forceActive(loc.path)
+}
+
+// helper function returns true if the secret path must be
+// read from the active node.
+func (p *plugin) forceActive(path string) bool {
+	for _, prefix := range p.active {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function returns a request callback that sends
+// the request to the given address.
+func withAddress(addr *url.URL) api.RequestCallback {
+	return func(req *api.Request) {
+		req.URL.Scheme = addr.Scheme
+		req.URL.Host = addr.Host
+	}
+}
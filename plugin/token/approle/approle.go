@@ -8,9 +8,10 @@ package approle
 
 import (
 	"context"
-	"strconv"
 	"time"
 
+	"github.com/drone/drone-vault/plugin/token"
+
 	"github.com/hashicorp/vault/api"
 	"github.com/sirupsen/logrus"
 )
@@ -18,124 +19,124 @@ import (
 // Name that identifies the auth method.
 const Name = "approle"
 
+// Config configures the auth method.
+type Config struct {
+	RoleID     string        `envconfig:"VAULT_APPROLE_ID"`
+	Secret     string        `envconfig:"VAULT_APPROLE_SECRET"`
+	SecretFile string        `envconfig:"VAULT_APPROLE_SECRET_FILE"`
+	Wrapped    bool          `envconfig:"VAULT_APPROLE_SECRET_WRAPPED"`
+	TTL        time.Duration `envconfig:"VAULT_TOKEN_TTL"`
+}
+
+func init() {
+	token.Register(Name, func(client *api.Client, prefix string) (token.Authenticator, error) {
+		config := Config{}
+		if err := token.Process(prefix, &config, "VAULT_APPROLE_ID"); err != nil {
+			return nil, err
+		}
+		// the secret id is read from the environment or a
+		// file, and is optionally delivered as a response
+		// wrapping token that is unwrapped on first login.
+		secretID, err := ReadSecretID(
+			context.Background(),
+			client,
+			config.Secret,
+			config.SecretFile,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		r := NewRenewer(client, config.RoleID, secretID, config.TTL)
+		r.wrapped = config.Wrapped
+		return r, nil
+	})
+}
+
 type (
-	// Renewer renews the Kubernetes token.
+	// Renewer authenticates with the approle auth method
+	// and renews the token.
 	Renewer struct {
+		*token.Lease
+
 		client   *api.Client
 		roleId   string
 		secretId string
-		ttl      int
+		wrapped  bool
 	}
 )
 
-// NewRenewer returns a new Kubernetes token provider
-// that renews the token on expiration.
+// NewRenewer returns a new approle token provider. The
+// token is renewed for the ttl, and a new token must be
+// requested when the token cannot be renewed for the ttl.
 func NewRenewer(client *api.Client, roleId string, secretId string, ttl time.Duration) *Renewer {
 	return &Renewer{
+		Lease:    token.NewLease(client, ttl),
 		client:   client,
 		roleId:   roleId,
 		secretId: secretId,
-		ttl:      int(ttl.Seconds()),
 	}
 }
 
-// Renew renews the Vault token.
-func (r *Renewer) Renew(ctx context.Context) error {
-	// create the vault endpoint address.
-	path := "auth/token/renew-self"
+// Login requests a new Vault token.
+func (r *Renewer) Login(ctx context.Context) error {
+	path := "auth/approle/login"
 
-	if r.client.Token() == "" {
-		logrus.Infoln("vault approle: no existing token, fetching one")
-		return r.NewToken(ctx)
+	// the wrapped secret id is unwrapped on the first
+	// successful login attempt, and the unwrapped secret id
+	// is kept for subsequent logins. if vault is unreachable
+	// the unwrap is retried with the next login.
+	if r.wrapped {
+		secretID, err := ReadSecretID(ctx, r.client, r.secretId, "", true)
+		if err != nil {
+			return err
+		}
+		r.secretId = secretID
+		r.wrapped = false
 	}
 
-	logrus.Debugln("vault approle: renewing token")
-
-	// Renew
+	logrus.Debugln("vault approle: generating new token")
 
-	resp, err := r.client.Logical().Write(path,
+	resp, err := r.client.Logical().WriteWithContext(ctx, path,
 		map[string]interface{}{
-			"increment": strconv.Itoa(r.ttl),
+			"role_id":   r.roleId,
+			"secret_id": r.secretId,
 		})
-	if err != nil {
-		logrus.WithError(err).Errorln("vault approle: token could not be renewed")
-		return r.NewToken(ctx)
+	if isSecretInvalid(err) {
+		logrus.WithError(err).Errorln(ErrSecretConsumed)
+		return ErrSecretConsumed
 	}
-
-	if resp.Auth.LeaseDuration < r.ttl {
-		logrus.Infoln("vault approle: token could not be renewed for desired ttl")
-		logrus.Infoln("vault approle: will request new token")
-		return r.NewToken(ctx)
+	if err != nil {
+		logrus.WithError(err).
+			Errorln("vault approle: cannot request vault token")
+		return err
 	}
 
-	if resp == nil {
-		logrus.Errorln("expected a response for login")
-		return nil
-	}
-	if resp.Auth == nil {
-		logrus.Errorln("expected auth object from response")
-		return nil
+	if err := r.Set(resp); err != nil {
+		logrus.WithError(err).
+			Errorln("vault approle: cannot read token from response")
+		return err
 	}
-	if resp.Auth.ClientToken == "" {
-		logrus.Errorln("expected a client token")
-		return nil
-	}
-
-	r.client.SetToken(resp.Auth.ClientToken)
-	ttl := time.Duration(resp.Auth.LeaseDuration) * time.Second
 
-	logrus.WithField("ttl", ttl).
-		Debugln("vault approle: existing token valid")
+	logrus.WithField("ttl", r.TTL()).
+		Debugln("vault approle: token received")
 
 	return nil
 }
 
-func (r *Renewer) NewToken(ctx context.Context) error {
-	path := "auth/approle/login"
-
-	logrus.Debugln("vault approle: generating new token")
+// Renew renews the Vault token.
+func (r *Renewer) Renew(ctx context.Context) error {
+	logrus.Debugln("vault approle: renewing token")
 
-	resp, err := r.client.Logical().Write(path,
-		map[string]interface{}{
-			"role_id":   r.roleId,
-			"secret_id": r.secretId,
-		})
+	err := r.Lease.Renew(ctx)
 	if err != nil {
-		logrus.Fatalln(err)
+		logrus.WithError(err).
+			Errorln("vault approle: token could not be renewed")
+		return err
 	}
 
-	if resp == nil {
-		logrus.Errorln("expected a response for login")
-		return nil
-	}
-	if resp.Auth == nil {
-		logrus.Errorln("expected auth object from response")
-		return nil
-	}
-	if resp.Auth.ClientToken == "" {
-		logrus.Errorln("expected a client token")
-		return nil
-	}
-
-	r.client.SetToken(resp.Auth.ClientToken)
-	ttl := time.Duration(resp.Auth.LeaseDuration) * time.Second
-	logrus.WithField("ttl", ttl).
-		Debugln("vault approle: token received")
+	logrus.WithField("ttl", r.TTL()).
+		Debugln("vault approle: existing token valid")
 
 	return nil
 }
-
-// Run performs token renewal at scheduled intervals.
-func (r *Renewer) Run(ctx context.Context, renew time.Duration) error {
-	if renew == 0 {
-		renew = time.Hour
-	}
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(renew):
-			r.Renew(ctx)
-		}
-	}
-}
@@ -17,6 +17,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/drone/drone-vault/plugin/token"
 	"github.com/hashicorp/vault/api"
 )
 
@@ -79,7 +80,10 @@ func TestVaultApproleRenewNoToken(t *testing.T) {
 	}
 
 	r := NewRenewer(client, roleId, secretId, ttl)
-	err := r.Renew(noContext)
+	if err := r.Renew(noContext); err != token.ErrNoToken {
+		t.Errorf("expected no token error, got %v", err)
+	}
+	err := r.Login(noContext)
 
 	if err != nil {
 		t.Error(err)
@@ -109,7 +113,7 @@ func TestVaultApproleNewToken(t *testing.T) {
 	client.SetToken(renewToken)
 
 	r := NewRenewer(client, roleId, secretId, ttl)
-	err := r.NewToken(noContext)
+	err := r.Login(noContext)
 
 	if err != nil {
 		t.Error(err)
@@ -178,7 +182,10 @@ func TestVaultApproleRenewLowerTTL(t *testing.T) {
 	client.SetToken(renewToken)
 
 	r := NewRenewer(client, roleId, secretId, ttl)
-	err := r.Renew(noContext)
+	if err := r.Renew(noContext); err != token.ErrTTLCapped {
+		t.Errorf("expected ttl capped error, got %v", err)
+	}
+	err := r.Login(noContext)
 
 	if err != nil {
 		t.Error(err)
@@ -0,0 +1,106 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package approle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrSecretConsumed is returned when vault rejects the secret
+// id during login, because it has been used the maximum number
+// of times, has expired, or has been revoked.
+var ErrSecretConsumed = errors.New("vault approle: secret id is invalid or has been consumed, a new secret id must be delivered")
+
+// ReadSecretID returns the secret id from the named file or,
+// if no file is set, the secret id value. If wrapped is true,
+// the secret id is a response-wrapping token, which is
+// unwrapped to retrieve the secret id. A wrapping token can
+// only be unwrapped once, so this should be called once, and
+// the unwrapped secret id kept.
+func ReadSecretID(ctx context.Context, client *api.Client, value, file string, wrapped bool) (string, error) {
+	if file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		value = string(bytes.TrimSpace(b))
+	}
+	if value == "" {
+		return "", errors.New("vault approle: missing secret id")
+	}
+	if !wrapped {
+		return value, nil
+	}
+
+	logrus.Debugln("vault approle: unwrapping secret id")
+
+	// the wrapping token is used to authenticate the unwrap
+	// request, so a clone of the client is used to avoid
+	// replacing the client token.
+	clone, err := client.Clone()
+	if err != nil {
+		return "", err
+	}
+	clone.ClearToken()
+	resp, err := clone.Logical().UnwrapWithContext(ctx, value)
+	if isWrappingInvalid(err) {
+		logrus.WithError(err).Errorln(ErrSecretConsumed)
+		return "", ErrSecretConsumed
+	}
+	if err != nil {
+		logrus.WithError(err).
+			Errorln("vault approle: cannot unwrap secret id")
+		return "", err
+	}
+	if resp == nil || resp.Data == nil {
+		return "", errors.New("vault approle: expected a wrapped secret id")
+	}
+	secretID, _ := resp.Data["secret_id"].(string)
+	if secretID == "" {
+		return "", errors.New("vault approle: expected a wrapped secret id")
+	}
+
+	logrus.Debugln("vault approle: secret id unwrapped")
+	return secretID, nil
+}
+
+// helper function returns true if vault rejected the unwrap
+// request because the wrapping token is invalid, for example
+// because it was already unwrapped.
+func isWrappingInvalid(err error) bool {
+	var resp *api.ResponseError
+	if !errors.As(err, &resp) || resp.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	for _, msg := range resp.Errors {
+		if strings.Contains(strings.ToLower(msg), "wrapping token is not valid") {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function returns true if vault rejected the login
+// because the secret id is invalid.
+func isSecretInvalid(err error) bool {
+	var resp *api.ResponseError
+	if !errors.As(err, &resp) || resp.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	for _, msg := range resp.Errors {
+		if strings.Contains(strings.ToLower(msg), "secret id") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package approle
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestReadSecretID(t *testing.T) {
+	got, err := ReadSecretID(noContext, nil, secretId, "", false)
+	if err != nil {
+		t.Error(err)
+	}
+	if got != secretId {
+		t.Errorf("Want secret id %s, got %s", secretId, got)
+	}
+}
+
+func TestReadSecretID_File(t *testing.T) {
+	got, err := ReadSecretID(noContext, nil, "", "testdata/secret_id", false)
+	if err != nil {
+		t.Error(err)
+	}
+	if got != secretId {
+		t.Errorf("Want secret id %s, got %s", secretId, got)
+	}
+}
+
+func TestReadSecretID_Missing(t *testing.T) {
+	if _, err := ReadSecretID(noContext, nil, "", "", false); err == nil {
+		t.Errorf("Expect missing secret id error")
+	}
+}
+
+func TestReadSecretID_Wrapped(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/sys/wrapping/unwrap" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		if got, want := r.Header.Get("X-Vault-Token"), "s.wrapping"; got != want {
+			t.Errorf("Want wrapping token %q, got %q", want, got)
+		}
+		data, _ := ioutil.ReadFile("testdata/unwrap.json")
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	client.ClearToken()
+
+	got, err := ReadSecretID(noContext, client, "s.wrapping", "", true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got != secretId {
+		t.Errorf("Want secret id %s, got %s", secretId, got)
+	}
+	if client.Token() != "" {
+		t.Errorf("Want client token unchanged, got %s", client.Token())
+	}
+}
+
+func TestReadSecretID_WrappedError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"errors":["wrapping token is not valid or does not exist"]}`))
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	if _, err := ReadSecretID(noContext, client, "s.wrapping", "", true); err == nil {
+		t.Errorf("Expect unwrap error")
+	}
+}
+
+// Test login fails clearly when the secret id is consumed.
+func TestLogin_SecretConsumed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"errors":["invalid secret id"]}`))
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	client.ClearToken()
+
+	r := NewRenewer(client, roleId, secretId, ttl)
+	if err := r.Login(noContext); err != ErrSecretConsumed {
+		t.Errorf("Want secret consumed error, got %v", err)
+	}
+}
+
+// Test login fails clearly when the wrapping token was
+// already unwrapped, for example after a restart.
+func TestLogin_WrappingConsumed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/sys/wrapping/unwrap" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		w.WriteHeader(400)
+		w.Write([]byte(`{"errors":["wrapping token is not valid or does not exist"]}`))
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	client.ClearToken()
+
+	r := NewRenewer(client, roleId, "s.wrapping", ttl)
+	r.wrapped = true
+	if err := r.Login(noContext); err != ErrSecretConsumed {
+		t.Errorf("Want secret consumed error, got %v", err)
+	}
+}
+
+// Test login returns an error, and does not exit, when the
+// vault server cannot be reached.
+func TestLogin_RequestError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL, MaxRetries: 0})
+	client.ClearToken()
+
+	r := NewRenewer(client, roleId, secretId, ttl)
+	if err := r.Login(noContext); err == nil {
+		t.Errorf("Want login error")
+	}
+}
+
+// Test the wrapped secret id is unwrapped on login, and the
+// unwrap is retried if vault is unavailable.
+func TestLogin_Wrapped(t *testing.T) {
+	var unwraps int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sys/wrapping/unwrap":
+			unwraps++
+			if unwraps == 1 {
+				w.WriteHeader(503)
+				return
+			}
+			data, _ := ioutil.ReadFile("testdata/unwrap.json")
+			w.Write(data)
+		case "/v1/auth/approle/login":
+			in := map[string]string{}
+			json.NewDecoder(r.Body).Decode(&in)
+			if got := in["secret_id"]; got != secretId {
+				t.Errorf("Want secret id %s, got %s", secretId, got)
+			}
+			data, _ := ioutil.ReadFile("testdata/new_token.json")
+			w.Write(data)
+		default:
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL, MaxRetries: 0})
+	r := NewRenewer(client, roleId, "s.wrapping", ttl)
+	r.wrapped = true
+
+	if err := r.Login(noContext); err == nil {
+		t.Errorf("Expect unwrap error")
+	}
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+	}
+	if unwraps != 2 {
+		t.Errorf("Want secret id unwrapped once, got %d requests", unwraps)
+	}
+}
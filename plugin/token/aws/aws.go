@@ -0,0 +1,158 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/drone/drone-vault/plugin/token"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/sirupsen/logrus"
+)
+
+// Name that identifies the auth method.
+const Name = "aws"
+
+// sts:GetCallerIdentity request body.
+const body = "Action=GetCallerIdentity&Version=2011-06-15"
+
+// Config configures the auth method.
+type Config struct {
+	Role   string `envconfig:"VAULT_AWS_ROLE"`
+	Mount  string `envconfig:"VAULT_AUTH_MOUNT_POINT"`
+	Header string `envconfig:"VAULT_AWS_HEADER_VALUE"`
+	Region string `envconfig:"VAULT_AWS_REGION"`
+}
+
+func init() {
+	token.Register(Name, func(client *api.Client, prefix string) (token.Authenticator, error) {
+		config := Config{}
+		if err := token.Process(prefix, &config, "VAULT_AWS_ROLE"); err != nil {
+			return nil, err
+		}
+		return NewRenewer(client, config.Role, config.Mount, config.Header, config.Region), nil
+	})
+}
+
+type (
+	// Renewer authenticates with the AWS IAM auth method
+	// and renews the token.
+	Renewer struct {
+		*token.Lease
+
+		client *api.Client
+		signer Signer
+
+		mount  string
+		role   string
+		header string
+		region string
+	}
+)
+
+// NewRenewer returns a new AWS IAM token provider that
+// renews the token on expiration, and logs in again when
+// the token can no longer be renewed.
+func NewRenewer(client *api.Client, role, mount, header, region string) *Renewer {
+	if mount == "" {
+		mount = Name
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &Renewer{
+		Lease:  token.NewLease(client, 0),
+		client: client,
+		signer: NewSigner(region),
+		mount:  mount,
+		role:   role,
+		header: header,
+		region: region,
+	}
+}
+
+// Login logs in to Vault with a signed sts:GetCallerIdentity
+// request and sets the client token.
+func (r *Renewer) Login(ctx context.Context) error {
+	path := fmt.Sprintf("auth/%s/login", r.mount)
+
+	logrus.WithField("path", path).
+		Debugln("vault aws: generating new token")
+
+	data, err := r.loginData(ctx)
+	if err != nil {
+		logrus.WithError(err).
+			Errorln("vault aws: cannot sign login request")
+		return err
+	}
+
+	resp, err := r.client.Logical().WriteWithContext(ctx, path, data)
+	if err != nil {
+		logrus.WithError(err).
+			WithField("path", path).
+			Errorln("vault aws: cannot request vault token")
+		return err
+	}
+	if err := r.Set(resp); err != nil {
+		return err
+	}
+
+	logrus.WithField("ttl", r.TTL()).
+		Debugln("vault aws: token received")
+
+	return nil
+}
+
+// Renew renews the Vault token.
+func (r *Renewer) Renew(ctx context.Context) error {
+	logrus.Debugln("vault aws: renewing token")
+
+	if err := r.Lease.Renew(ctx); err != nil {
+		logrus.WithError(err).Errorln("vault aws: token could not be renewed")
+		return err
+	}
+
+	logrus.WithField("ttl", r.TTL()).
+		Debugln("vault aws: existing token valid")
+
+	return nil
+}
+
+// helper function returns the login request payload, which
+// includes the signed sts:GetCallerIdentity request.
+func (r *Renewer) loginData(ctx context.Context) (map[string]interface{}, error) {
+	endpoint := "https://sts.amazonaws.com/"
+	if r.region != "us-east-1" {
+		endpoint = fmt.Sprintf("https://sts.%s.amazonaws.com/", r.region)
+	}
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	if r.header != "" {
+		req.Header.Set("X-Vault-AWS-IAM-Server-ID", r.header)
+	}
+	if err := r.signer.Sign(ctx, req, []byte(body)); err != nil {
+		return nil, err
+	}
+	headers, err := json.Marshal(req.Header)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"role":                    r.role,
+		"iam_http_request_method": req.Method,
+		"iam_request_url":         base64.StdEncoding.EncodeToString([]byte(endpoint)),
+		"iam_request_body":        base64.StdEncoding.EncodeToString([]byte(body)),
+		"iam_request_headers":     base64.StdEncoding.EncodeToString(headers),
+	}, nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+var noContext = context.Background()
+
+// fakeSigner signs requests with a static signature.
+type fakeSigner struct {
+	err error
+}
+
+func (s *fakeSigner) Sign(ctx context.Context, req *http.Request, body []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Signature=fake")
+	return nil
+}
+
+func TestLogin(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/aws/login" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		in := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&in)
+		if got, want := in["role"], "drone"; got != want {
+			t.Errorf("Want role %q, got %q", want, got)
+		}
+		if got, want := in["iam_http_request_method"], "POST"; got != want {
+			t.Errorf("Want method %q, got %q", want, got)
+		}
+		url, _ := base64.StdEncoding.DecodeString(in["iam_request_url"])
+		if got, want := string(url), "https://sts.amazonaws.com/"; got != want {
+			t.Errorf("Want url %q, got %q", want, got)
+		}
+		body, _ := base64.StdEncoding.DecodeString(in["iam_request_body"])
+		if got, want := string(body), "Action=GetCallerIdentity&Version=2011-06-15"; got != want {
+			t.Errorf("Want body %q, got %q", want, got)
+		}
+		raw, _ := base64.StdEncoding.DecodeString(in["iam_request_headers"])
+		headers := http.Header{}
+		json.Unmarshal(raw, &headers)
+		if got, want := headers.Get("Authorization"), "AWS4-HMAC-SHA256 Signature=fake"; got != want {
+			t.Errorf("Want signed request, got authorization %q", got)
+		}
+		if got, want := headers.Get("X-Vault-AWS-IAM-Server-ID"), "vault.example.com"; got != want {
+			t.Errorf("Want server id header %q, got %q", want, got)
+		}
+		data, _ := ioutil.ReadFile("testdata/login.json")
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	client.ClearToken()
+
+	r := NewRenewer(client, "drone", "", "vault.example.com", "")
+	r.signer = &fakeSigner{}
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := "s.OhZm4kQxf6K45Tg0bKNQbTJD"
+	if got := client.Token(); got != want {
+		t.Errorf("Want token %s, got %s", want, got)
+	}
+}
+
+func TestLogin_SignerError(t *testing.T) {
+	client, _ := api.NewClient(nil)
+	client.ClearToken()
+
+	r := NewRenewer(client, "drone", "", "", "")
+	r.signer = &fakeSigner{err: errNoCredentials}
+	if err := r.Login(noContext); !errors.Is(err, errNoCredentials) {
+		t.Errorf("Want no credentials error, got %v", err)
+	}
+}
+
+func TestRenew(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/token/renew-self" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		data, _ := ioutil.ReadFile("testdata/renew.json")
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	client.SetToken("s.zREhsyJT79kcuGbfrKsbyo0W")
+
+	r := NewRenewer(client, "drone", "", "", "")
+	r.signer = &fakeSigner{}
+	if err := r.Renew(noContext); err != nil {
+		t.Error(err)
+	}
+	if got, want := client.Token(), "s.zREhsyJT79kcuGbfrKsbyo0W"; got != want {
+		t.Errorf("Want token %s, got %s", want, got)
+	}
+}
+
+// Test a new token can be requested when the token can no
+// longer be renewed.
+func TestRenew_Login(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/token/renew-self":
+			w.WriteHeader(403)
+		case "/v1/auth/custom-aws/login":
+			data, _ := ioutil.ReadFile("testdata/login.json")
+			w.Write(data)
+		default:
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL, MaxRetries: 0})
+	client.SetToken("s.zREhsyJT79kcuGbfrKsbyo0W")
+
+	r := NewRenewer(client, "drone", "custom-aws", "", "eu-west-1")
+	r.signer = &fakeSigner{}
+	if err := r.Renew(noContext); err == nil {
+		t.Errorf("Want renew error")
+	}
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+	}
+	if got, want := client.Token(), "s.OhZm4kQxf6K45Tg0bKNQbTJD"; got != want {
+		t.Errorf("Want token %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// ecs task metadata endpoint.
+	ecsEndpoint = "http://169.254.170.2"
+
+	// ec2 instance metadata endpoint.
+	ec2Endpoint = "http://169.254.169.254"
+)
+
+// errNoCredentials is returned when no ambient credentials
+// are found.
+var errNoCredentials = errors.New("aws: no credentials found")
+
+type (
+	// Credentials represents the AWS credentials used to
+	// sign requests.
+	Credentials struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		SessionToken    string `json:"Token"`
+	}
+
+	// provider returns the ambient AWS credentials from the
+	// environment, the ECS task role or the EC2 instance
+	// profile, in that order.
+	provider struct {
+		client *http.Client
+		ecs    string
+		ec2    string
+	}
+)
+
+func newProvider() *provider {
+	return &provider{
+		client: &http.Client{Timeout: 5 * time.Second},
+		ecs:    ecsEndpoint,
+		ec2:    ec2Endpoint,
+	}
+}
+
+// find returns the ambient AWS credentials.
+func (p *provider) find(ctx context.Context) (*Credentials, error) {
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return &Credentials{
+			AccessKeyID:     id,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		return p.container(ctx, p.ecs+uri)
+	}
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI"); uri != "" {
+		return p.container(ctx, uri)
+	}
+	return p.instance(ctx)
+}
+
+// container returns the ECS task role credentials.
+func (p *provider) container(ctx context.Context, uri string) (*Credentials, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	out := new(Credentials)
+	err = p.do(req, out)
+	return out, err
+}
+
+// instance returns the EC2 instance profile credentials
+// using the v2 instance metadata service.
+func (p *provider) instance(ctx context.Context) (*Credentials, error) {
+	req, err := http.NewRequestWithContext(ctx, "PUT", p.ec2+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, errNoCredentials
+	}
+	defer res.Body.Close()
+	if res.StatusCode > 299 {
+		return nil, errNoCredentials
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	token := string(b)
+
+	path := p.ec2 + "/latest/meta-data/iam/security-credentials/"
+	req, err = http.NewRequestWithContext(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+	res, err = p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode > 299 {
+		return nil, errNoCredentials
+	}
+	b, err = ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	role := strings.TrimSpace(strings.SplitN(string(b), "\n", 2)[0])
+	if role == "" {
+		return nil, errNoCredentials
+	}
+
+	req, err = http.NewRequestWithContext(ctx, "GET", path+role, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+	out := new(Credentials)
+	err = p.do(req, out)
+	return out, err
+}
+
+func (p *provider) do(req *http.Request, out *Credentials) error {
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode > 299 {
+		return fmt.Errorf("aws: cannot fetch credentials: %s", res.Status)
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return err
+	}
+	if out.AccessKeyID == "" {
+		return errNoCredentials
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package aws
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Signer signs the sts:GetCallerIdentity request that is
+// sent to Vault to prove the AWS identity.
+type Signer interface {
+	Sign(ctx context.Context, req *http.Request, body []byte) error
+}
+
+// signer signs requests with the ambient AWS credentials
+// using the AWS Signature Version 4 algorithm.
+type signer struct {
+	region   string
+	provider *provider
+}
+
+// NewSigner returns a Signer that signs requests with the
+// ambient AWS credentials for the given region.
+func NewSigner(region string) Signer {
+	return &signer{
+		region:   region,
+		provider: newProvider(),
+	}
+}
+
+func (s *signer) Sign(ctx context.Context, req *http.Request, body []byte) error {
+	creds, err := s.provider.find(ctx)
+	if err != nil {
+		return err
+	}
+	sign(req, body, creds, s.region, "sts", time.Now())
+	return nil
+}
+
+// helper function signs the request using the AWS Signature
+// Version 4 algorithm. All request headers, and the host, are
+// included in the signature.
+func sign(req *http.Request, body []byte, creds *Credentials, region, service string, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102")
+	stamp := now.Format("20060102T150405Z")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", stamp)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	var names []string
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+
+	var headers strings.Builder
+	for _, name := range names {
+		values := req.Header.Values(name)
+		for i, value := range values {
+			values[i] = strings.TrimSpace(value)
+		}
+		headers.WriteString(name + ":" + strings.Join(values, ",") + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonical := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		headers.String(),
+		signed,
+		hashHex(body),
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	toSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		stamp,
+		scope,
+		hashHex([]byte(canonical)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signed, signature,
+	))
+}
+
+func hashHex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
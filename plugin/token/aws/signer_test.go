@@ -0,0 +1,120 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package aws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test the signature against the get-vanilla example from
+// the AWS Signature Version 4 test suite.
+func TestSign(t *testing.T) {
+	creds := &Credentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	now, _ := time.Parse("20060102T150405Z", "20150830T123600Z")
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	sign(req, nil, creds, "us-east-1", "service", now)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Want authorization\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestSign_SessionToken(t *testing.T) {
+	creds := &Credentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		SessionToken:    "session",
+	}
+	req, _ := http.NewRequest("POST", "https://sts.amazonaws.com/", nil)
+	sign(req, []byte(body), creds, "us-east-1", "sts", time.Now())
+
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "session" {
+		t.Errorf("Want session token header, got %q", got)
+	}
+}
+
+func TestProvider_Env(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	t.Setenv("AWS_SESSION_TOKEN", "session")
+
+	creds, err := newProvider().find(noContext)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if creds.AccessKeyID != "AKIDEXAMPLE" || creds.SessionToken != "session" {
+		t.Errorf("Want credentials from environment, got %+v", creds)
+	}
+}
+
+func TestProvider_Container(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/credentials/task" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		w.Write([]byte(`{"AccessKeyId":"AKIDEXAMPLE","SecretAccessKey":"secret","Token":"session"}`))
+	}))
+	defer ts.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "/v2/credentials/task")
+
+	p := newProvider()
+	p.ecs = ts.URL
+	creds, err := p.find(noContext)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if creds.AccessKeyID != "AKIDEXAMPLE" || creds.SessionToken != "session" {
+		t.Errorf("Want credentials from container, got %+v", creds)
+	}
+}
+
+func TestProvider_Instance(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest/api/token":
+			w.Write([]byte("imds-token"))
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "imds-token" {
+			w.WriteHeader(401)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest/meta-data/iam/security-credentials/":
+			w.Write([]byte("drone-role"))
+		case "/latest/meta-data/iam/security-credentials/drone-role":
+			w.Write([]byte(`{"AccessKeyId":"AKIDEXAMPLE","SecretAccessKey":"secret","Token":"session"}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "")
+
+	p := newProvider()
+	p.ec2 = ts.URL
+	creds, err := p.find(noContext)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if creds.AccessKeyID != "AKIDEXAMPLE" || creds.SessionToken != "session" {
+		t.Errorf("Want credentials from instance profile, got %+v", creds)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drone/drone-vault/plugin/token"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/sirupsen/logrus"
+)
+
+// Name that identifies the auth method.
+const Name = "azure"
+
+// default resource for which the managed identity
+// token is requested.
+const defaultResource = "https://management.azure.com/"
+
+func init() {
+	token.Register(Name, func(client *api.Client, prefix string) (token.Authenticator, error) {
+		config := Config{}
+		if err := token.Process(prefix, &config, "VAULT_AZURE_ROLE"); err != nil {
+			return nil, err
+		}
+		return NewRenewer(client, config), nil
+	})
+}
+
+type (
+	// Config configures the Azure auth method. The
+	// subscription, resource group and virtual machine
+	// metadata is fetched from the instance metadata
+	// service when not set.
+	Config struct {
+		Role           string `envconfig:"VAULT_AZURE_ROLE"`
+		Mount          string `envconfig:"VAULT_AUTH_MOUNT_POINT"`
+		Resource       string `envconfig:"VAULT_AZURE_RESOURCE"`
+		ClientID       string `envconfig:"VAULT_AZURE_CLIENT_ID"`
+		SubscriptionID string `envconfig:"VAULT_AZURE_SUBSCRIPTION_ID"`
+		ResourceGroup  string `envconfig:"VAULT_AZURE_RESOURCE_GROUP"`
+		VMName         string `envconfig:"VAULT_AZURE_VM_NAME"`
+		VMSSName       string `envconfig:"VAULT_AZURE_VMSS_NAME"`
+	}
+
+	// Renewer renews the Azure token.
+	Renewer struct {
+		*token.Lease
+
+		client   *api.Client
+		config   Config
+		metadata string
+	}
+)
+
+// NewRenewer returns a new Azure token provider that logs
+// in again before the token expires.
+func NewRenewer(client *api.Client, config Config) *Renewer {
+	if config.Mount == "" {
+		config.Mount = Name
+	}
+	if config.Resource == "" {
+		config.Resource = defaultResource
+	}
+	return &Renewer{
+		Lease:    token.NewLease(client, 0),
+		client:   client,
+		config:   config,
+		metadata: metadataEndpoint,
+	}
+}
+
+// Login logs in to Vault and sets the client token.
+func (r *Renewer) Login(ctx context.Context) error {
+	path := fmt.Sprintf("auth/%s/login", r.config.Mount)
+
+	logrus.WithField("resource", r.config.Resource).
+		Debugln("azure: requesting managed identity token")
+
+	jwt, err := fetchToken(ctx, r.metadata, r.config.Resource, r.config.ClientID)
+	if err != nil {
+		logrus.WithError(err).
+			Errorln("azure: cannot request managed identity token")
+		return err
+	}
+
+	data := map[string]interface{}{
+		"role":                r.config.Role,
+		"jwt":                 jwt,
+		"subscription_id":     r.config.SubscriptionID,
+		"resource_group_name": r.config.ResourceGroup,
+		"vm_name":             r.config.VMName,
+		"vmss_name":           r.config.VMSSName,
+	}
+
+	// the subscription, resource group and virtual machine
+	// are fetched from the instance metadata service if not
+	// configured, since vault verifies them against the
+	// role bindings.
+	if r.config.SubscriptionID == "" || r.config.ResourceGroup == "" ||
+		(r.config.VMName == "" && r.config.VMSSName == "") {
+		compute, err := fetchInstance(ctx, r.metadata)
+		if err != nil {
+			logrus.WithError(err).
+				Errorln("azure: cannot request instance metadata")
+			return err
+		}
+		if r.config.SubscriptionID == "" {
+			data["subscription_id"] = compute.SubscriptionID
+		}
+		if r.config.ResourceGroup == "" {
+			data["resource_group_name"] = compute.ResourceGroup
+		}
+		if r.config.VMName == "" && r.config.VMSSName == "" {
+			if compute.VMSSName != "" {
+				data["vmss_name"] = compute.VMSSName
+			} else {
+				data["vm_name"] = compute.Name
+			}
+		}
+	}
+
+	logrus.WithField("path", path).
+		Debugln("azure: requesting vault token")
+
+	resp, err := r.client.Logical().WriteWithContext(ctx, path, data)
+	if err != nil {
+		logrus.WithError(err).
+			WithField("path", path).
+			Errorln("azure: cannot request vault token")
+		return err
+	}
+	if err := r.Set(resp); err != nil {
+		logrus.WithError(err).
+			Errorln("azure: cannot read vault token")
+		return err
+	}
+
+	logrus.WithField("ttl", r.TTL()).
+		Debugln("azure: token received")
+
+	return nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var noContext = context.Background()
+
+func newMetadataServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			t.Errorf("Want metadata header")
+		}
+		switch r.URL.Path {
+		case "/metadata/identity/oauth2/token":
+			if got, want := r.URL.Query().Get("resource"), "https://vault.example.com"; got != want {
+				t.Errorf("Want resource %q, got %q", want, got)
+			}
+			data, _ := ioutil.ReadFile("testdata/identity.json")
+			w.Write(data)
+		case "/metadata/instance":
+			data, _ := ioutil.ReadFile("testdata/instance.json")
+			w.Write(data)
+		default:
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+	}))
+}
+
+func TestLogin(t *testing.T) {
+	metadata := newMetadataServer(t)
+	defer metadata.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/azure/login" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		got := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&got)
+		want := map[string]string{
+			"role":                "dev-role",
+			"jwt":                 "header.claims.signature",
+			"subscription_id":     "a2b3c4d5-e6f7-4a8b-9c0d-1e2f3a4b5c6d",
+			"resource_group_name": "drone",
+			"vm_name":             "drone-vault-0",
+			"vmss_name":           "",
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf(diff)
+		}
+		data, _ := ioutil.ReadFile("testdata/token.json")
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+
+	r := NewRenewer(client, Config{
+		Role:     "dev-role",
+		Resource: "https://vault.example.com",
+	})
+	r.metadata = metadata.URL
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := "62b858f9-529c-6b26-e0b8-0457b6aacdb4"
+	if got := client.Token(); got != want {
+		t.Errorf("Want token %s, got %s", want, got)
+	}
+}
+
+func TestLogin_Configured(t *testing.T) {
+	metadata := newMetadataServer(t)
+	defer metadata.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/azure-prod/login" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		got := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&got)
+		if got["subscription_id"] != "0000" || got["resource_group_name"] != "prod" || got["vmss_name"] != "runners" {
+			t.Errorf("Want configured metadata, got %v", got)
+		}
+		data, _ := ioutil.ReadFile("testdata/token.json")
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+
+	r := NewRenewer(client, Config{
+		Role:           "dev-role",
+		Mount:          "azure-prod",
+		Resource:       "https://vault.example.com",
+		SubscriptionID: "0000",
+		ResourceGroup:  "prod",
+		VMSSName:       "runners",
+	})
+	r.metadata = metadata.URL
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test a configured resource group is not replaced by the
+// instance metadata when the subscription is not configured.
+func TestLogin_PartiallyConfigured(t *testing.T) {
+	metadata := newMetadataServer(t)
+	defer metadata.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&got)
+		if got["subscription_id"] != "a2b3c4d5-e6f7-4a8b-9c0d-1e2f3a4b5c6d" || got["resource_group_name"] != "prod" {
+			t.Errorf("Want configured resource group, got %v", got)
+		}
+		data, _ := ioutil.ReadFile("testdata/token.json")
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+
+	r := NewRenewer(client, Config{
+		Role:          "dev-role",
+		Resource:      "https://vault.example.com",
+		ResourceGroup: "prod",
+	})
+	r.metadata = metadata.URL
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test the virtual machine name is read from the instance
+// metadata when the subscription and resource group are
+// configured.
+func TestLogin_VMName(t *testing.T) {
+	metadata := newMetadataServer(t)
+	defer metadata.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&got)
+		want := map[string]string{
+			"role":                "dev-role",
+			"jwt":                 "header.claims.signature",
+			"subscription_id":     "0000",
+			"resource_group_name": "prod",
+			"vm_name":             "drone-vault-0",
+			"vmss_name":           "",
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf(diff)
+		}
+		data, _ := ioutil.ReadFile("testdata/token.json")
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+
+	r := NewRenewer(client, Config{
+		Role:           "dev-role",
+		Resource:       "https://vault.example.com",
+		SubscriptionID: "0000",
+		ResourceGroup:  "prod",
+	})
+	r.metadata = metadata.URL
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLogin_MetadataError(t *testing.T) {
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+	}))
+	defer metadata.Close()
+
+	r := NewRenewer(nil, Config{Role: "dev-role"})
+	r.metadata = metadata.URL
+	if err := r.Login(noContext); err == nil {
+		t.Errorf("Expect metadata error")
+	}
+}
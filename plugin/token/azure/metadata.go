@@ -0,0 +1,93 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// azure instance metadata service endpoint.
+const metadataEndpoint = "http://169.254.169.254"
+
+// http client used to request the instance metadata service.
+// The timeout ensures a metadata service that does not
+// respond cannot block login.
+var httpClient = &http.Client{Timeout: 5 * time.Second}
+
+type (
+	// managed identity token response.
+	identity struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	// instance metadata response.
+	instance struct {
+		Compute compute `json:"compute"`
+	}
+
+	// instance compute metadata.
+	compute struct {
+		Name           string `json:"name"`
+		ResourceGroup  string `json:"resourceGroupName"`
+		SubscriptionID string `json:"subscriptionId"`
+		VMSSName       string `json:"vmScaleSetName"`
+	}
+)
+
+// helper function fetches a managed identity token for the
+// resource from the instance metadata service. If the client
+// id is set, a token for the user-assigned identity is
+// requested.
+func fetchToken(ctx context.Context, endpoint, resource, clientID string) (string, error) {
+	params := url.Values{}
+	params.Set("api-version", "2018-02-01")
+	params.Set("resource", resource)
+	if clientID != "" {
+		params.Set("client_id", clientID)
+	}
+	out := new(identity)
+	err := get(ctx, endpoint+"/metadata/identity/oauth2/token?"+params.Encode(), out)
+	if err != nil {
+		return "", err
+	}
+	if out.AccessToken == "" {
+		return "", errors.New("azure: expected an access token")
+	}
+	return out.AccessToken, nil
+}
+
+// helper function fetches the compute metadata from the
+// instance metadata service.
+func fetchInstance(ctx context.Context, endpoint string) (*compute, error) {
+	out := new(instance)
+	err := get(ctx, endpoint+"/metadata/instance?api-version=2017-08-01", out)
+	if err != nil {
+		return nil, err
+	}
+	return &out.Compute, nil
+}
+
+func get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Metadata", "true")
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode > 299 {
+		return fmt.Errorf("azure: metadata request failed: %s", res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
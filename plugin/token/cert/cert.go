@@ -0,0 +1,138 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/drone/drone-vault/plugin/token"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/sirupsen/logrus"
+)
+
+// Name that identifies the auth method.
+const Name = "cert"
+
+// ErrMissingCert is returned when the client certificate or
+// key file is not configured.
+var ErrMissingCert = errors.New("cert: client certificate and key are required")
+
+// Config configures the auth method.
+type Config struct {
+	Role  string `envconfig:"VAULT_CERT_ROLE"`
+	Mount string `envconfig:"VAULT_AUTH_MOUNT_POINT"`
+	Cert  string `envconfig:"VAULT_CLIENT_CERT"`
+	Key   string `envconfig:"VAULT_CLIENT_KEY"`
+}
+
+func init() {
+	token.Register(Name, func(client *api.Client, prefix string) (token.Authenticator, error) {
+		config := Config{}
+		if err := token.Process(prefix, &config); err != nil {
+			return nil, err
+		}
+		if config.Cert == "" || config.Key == "" {
+			return nil, ErrMissingCert
+		}
+		return NewRenewer(client, config.Role, config.Mount, config.Cert, config.Key), nil
+	})
+}
+
+type (
+	// Renewer renews the TLS certificate token.
+	Renewer struct {
+		*token.Lease
+
+		client    *api.Client
+		keypair   *keypair
+		transport *http.Transport
+
+		mount string
+		role  string
+	}
+)
+
+// NewRenewer returns a new TLS certificate token provider
+// that logs in again before the token expires. The client
+// certificate is reloaded from disk when it is rotated.
+func NewRenewer(client *api.Client, role, mount, certFile, keyFile string) *Renewer {
+	if mount == "" {
+		mount = Name
+	}
+	r := &Renewer{
+		Lease:  token.NewLease(client, 0),
+		client: client,
+		keypair: &keypair{
+			certFile: certFile,
+			keyFile:  keyFile,
+		},
+		mount: mount,
+		role:  role,
+	}
+
+	// the client certificate is presented by the vault
+	// client for every tls handshake, so it is installed
+	// in the shared http transport.
+	if t, ok := client.CloneConfig().HttpClient.Transport.(*http.Transport); ok {
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.GetClientCertificate = r.keypair.get
+		r.transport = t
+	}
+	return r
+}
+
+// Login logs in to Vault and sets the client token.
+func (r *Renewer) Login(ctx context.Context) error {
+	path := fmt.Sprintf("auth/%s/login", r.mount)
+
+	reloaded, err := r.keypair.load()
+	if err != nil {
+		logrus.WithError(err).
+			WithField("cert", r.keypair.certFile).
+			Errorln("cert: cannot load client certificate")
+		return err
+	}
+
+	// existing connections were established with the
+	// previous certificate, and are closed so that the
+	// rotated certificate is used to login.
+	if reloaded && r.transport != nil {
+		logrus.WithField("cert", r.keypair.certFile).
+			Debugln("cert: client certificate loaded")
+		r.transport.CloseIdleConnections()
+	}
+
+	logrus.WithField("path", path).
+		Debugln("cert: requesting vault token")
+
+	data := map[string]interface{}{}
+	if r.role != "" {
+		data["name"] = r.role
+	}
+	resp, err := r.client.Logical().WriteWithContext(ctx, path, data)
+	if err != nil {
+		logrus.WithError(err).
+			WithField("path", path).
+			Errorln("cert: cannot request vault token")
+		return err
+	}
+	if err := r.Set(resp); err != nil {
+		logrus.WithError(err).
+			Errorln("cert: cannot read vault token")
+		return err
+	}
+
+	logrus.WithField("ttl", r.TTL()).
+		Debugln("cert: token received")
+
+	return nil
+}
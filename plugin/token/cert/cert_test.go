@@ -0,0 +1,131 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drone/drone-vault/plugin/token"
+
+	"github.com/hashicorp/vault/api"
+)
+
+var noContext = context.Background()
+
+func TestLogin(t *testing.T) {
+	var names []string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/cert/login" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		in := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&in)
+		if got, want := in["name"], "drone"; got != want {
+			t.Errorf("Want role %q, got %q", want, got)
+		}
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(400)
+			return
+		}
+		names = append(names, r.TLS.PeerCertificates[0].Subject.CommonName)
+		data, _ := ioutil.ReadFile("testdata/token.json")
+		w.Write(data)
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	writeCert(t, certFile, keyFile, "drone-1", time.Now().Add(-time.Hour))
+
+	client, _ := api.NewClient(&api.Config{
+		Address:    ts.URL,
+		HttpClient: ts.Client(),
+	})
+
+	r := NewRenewer(client, "drone", "", certFile, keyFile)
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := "62b858f9-529c-6b26-e0b8-0457b6aacdb4"
+	if got := client.Token(); got != want {
+		t.Errorf("Want token %s, got %s", want, got)
+	}
+
+	// the rotated certificate must be used for the next login.
+	writeCert(t, certFile, keyFile, "drone-2", time.Now())
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(names) != 2 || names[0] != "drone-1" || names[1] != "drone-2" {
+		t.Errorf("Want rotated client certificate, got %v", names)
+	}
+}
+
+func TestLogin_CertError(t *testing.T) {
+	client, _ := api.NewClient(nil)
+	r := NewRenewer(client, "drone", "", "testdata/does-not-exist.crt", "testdata/does-not-exist.key")
+	err := r.Login(noContext)
+	if _, ok := err.(*os.PathError); !ok {
+		t.Errorf("Expect PathError got %v", err)
+	}
+}
+
+// helper function writes a self-signed client certificate
+// and key with the common name and modification time.
+func writeCert(t *testing.T, certFile, keyFile, name string, mod time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := x509.MarshalECPrivateKey(key)
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b}), 0600)
+	os.Chtimes(certFile, mod, mod)
+	os.Chtimes(keyFile, mod, mod)
+}
+
+func TestFactory_MissingCert(t *testing.T) {
+	t.Setenv("VAULT_CLIENT_CERT", "testdata/client.crt")
+	t.Setenv("VAULT_CLIENT_KEY", "")
+
+	factory, _ := token.Lookup(Name)
+	client, _ := api.NewClient(nil)
+	if _, err := factory(client, ""); err != ErrMissingCert {
+		t.Errorf("Want missing certificate error, got %v", err)
+	}
+}
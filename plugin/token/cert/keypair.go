@@ -0,0 +1,75 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package cert
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+// keypair loads the client certificate from disk, and
+// reloads the certificate when the files are modified.
+type keypair struct {
+	sync.Mutex
+	certFile string
+	keyFile  string
+
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+// load reads the certificate from disk if the files were
+// modified since last read, and returns true if the
+// certificate was reloaded.
+func (k *keypair) load() (bool, error) {
+	k.Lock()
+	defer k.Unlock()
+	modified, err := k.modTime()
+	if err != nil {
+		return false, err
+	}
+	if k.cert != nil && modified.Equal(k.modified) {
+		return false, nil
+	}
+	cert, err := tls.LoadX509KeyPair(k.certFile, k.keyFile)
+	if err != nil {
+		return false, err
+	}
+	k.cert = &cert
+	k.modified = modified
+	return true, nil
+}
+
+// get returns the client certificate for the tls handshake.
+// The certificate is always returned, regardless of the
+// acceptable authorities sent by the server, since the cert
+// auth method may trust authorities unknown to the server.
+func (k *keypair) get(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if _, err := k.load(); err != nil {
+		return nil, err
+	}
+	k.Lock()
+	defer k.Unlock()
+	return k.cert, nil
+}
+
+// helper function returns the latest modification time of
+// the certificate and key files.
+func (k *keypair) modTime() (time.Time, error) {
+	certInfo, err := os.Stat(k.certFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	keyInfo, err := os.Stat(k.keyFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if keyInfo.ModTime().After(certInfo.ModTime()) {
+		return keyInfo.ModTime(), nil
+	}
+	return certInfo.ModTime(), nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package token
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Process populates the auth method configuration from the
+// environment. If the prefix is set, the configuration is
+// read only from prefixed variables, for example
+// PROD_VAULT_APPROLE_ID, so that the credentials of one vault
+// backend are never used to authenticate with another, and
+// the required variables must be set.
+func Process(prefix string, spec interface{}, required ...string) error {
+	if err := envconfig.Process(prefix, spec); err != nil {
+		return err
+	}
+	if prefix == "" {
+		return nil
+	}
+
+	// envconfig falls back to the unprefixed variable when
+	// the prefixed variable is unset. the value is reset to
+	// prevent the fallback.
+	v := reflect.ValueOf(spec).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("envconfig")
+		if tag == "" {
+			continue
+		}
+		if _, ok := os.LookupEnv(prefixed(prefix, tag)); !ok {
+			v.Field(i).Set(reflect.Zero(t.Field(i).Type))
+		}
+	}
+	for _, name := range required {
+		if key := prefixed(prefix, name); os.Getenv(key) == "" {
+			return fmt.Errorf("token: missing %s", key)
+		}
+	}
+	return nil
+}
+
+// helper function returns the prefixed variable name.
+func prefixed(prefix, name string) string {
+	return strings.ToUpper(prefix + "_" + name)
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package token
+
+import (
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Role   string        `envconfig:"VAULT_TEST_ROLE"`
+	Secret string        `envconfig:"VAULT_TEST_SECRET"`
+	TTL    time.Duration `envconfig:"VAULT_TOKEN_TTL"`
+}
+
+// Test the configuration is read from unprefixed variables
+// without a prefix.
+func TestProcess(t *testing.T) {
+	t.Setenv("VAULT_TEST_ROLE", "drone")
+	t.Setenv("VAULT_TEST_SECRET", "f7e3a4a1")
+
+	config := testConfig{}
+	if err := Process("", &config, "VAULT_TEST_ROLE"); err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := config.Secret, "f7e3a4a1"; got != want {
+		t.Errorf("Want secret %q, got %q", want, got)
+	}
+}
+
+// Test the configuration of a named vault backend does not
+// fall back to unprefixed variables.
+func TestProcess_Prefix(t *testing.T) {
+	t.Setenv("VAULT_TEST_SECRET", "f7e3a4a1")
+	t.Setenv("VAULT_TOKEN_TTL", "1h")
+	t.Setenv("PROD_VAULT_TEST_ROLE", "drone")
+
+	config := testConfig{}
+	if err := Process("PROD", &config, "VAULT_TEST_ROLE"); err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := config.Role, "drone"; got != want {
+		t.Errorf("Want role %q, got %q", want, got)
+	}
+	if config.Secret != "" {
+		t.Errorf("Want unprefixed secret ignored, got %q", config.Secret)
+	}
+	if config.TTL != 0 {
+		t.Errorf("Want unprefixed ttl ignored, got %v", config.TTL)
+	}
+}
+
+// Test a named vault backend fails when a required variable
+// is only set without the prefix.
+func TestProcess_PrefixRequired(t *testing.T) {
+	t.Setenv("VAULT_TEST_ROLE", "drone")
+
+	config := testConfig{}
+	err := Process("PROD", &config, "VAULT_TEST_ROLE")
+	if err == nil {
+		t.Errorf("Want error for missing variable")
+		return
+	}
+	if got, want := err.Error(), "token: missing PROD_VAULT_TEST_ROLE"; got != want {
+		t.Errorf("Want error %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/drone/drone-vault/plugin/token"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/sirupsen/logrus"
+)
+
+// Name that identifies the auth method.
+const Name = "file"
+
+// interval at which the token file is checked for changes.
+const pollInterval = 5 * time.Second
+
+// Config configures the auth method.
+type Config struct {
+	Path string `envconfig:"VAULT_TOKEN_FILE"`
+}
+
+func init() {
+	token.Register(Name, func(client *api.Client, prefix string) (token.Authenticator, error) {
+		config := Config{}
+		if err := token.Process(prefix, &config, "VAULT_TOKEN_FILE"); err != nil {
+			return nil, err
+		}
+		return NewRenewer(client, config.Path), nil
+	})
+}
+
+type (
+	// Renewer reads the Vault token from a file, for example
+	// a Vault Agent token sink, and reloads the token when
+	// the file changes.
+	Renewer struct {
+		client *api.Client
+		path   string
+
+		mu       sync.Mutex
+		modified time.Time
+		size     int64
+	}
+)
+
+// NewRenewer returns a new file token provider that reloads
+// the token when the file changes.
+func NewRenewer(client *api.Client, path string) *Renewer {
+	return &Renewer{
+		client: client,
+		path:   path,
+	}
+}
+
+// Login reads the Vault token from the file.
+func (r *Renewer) Login(ctx context.Context) error {
+	logrus.WithField("path", r.path).
+		Debugln("vault file: reading token")
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		logrus.WithError(err).
+			WithField("path", r.path).
+			Errorln("vault file: cannot read token")
+		return err
+	}
+	b, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		logrus.WithError(err).
+			WithField("path", r.path).
+			Errorln("vault file: cannot read token")
+		return err
+	}
+
+	// the token file may be empty while the sink is being
+	// written, in which case the existing token is kept.
+	value := string(bytes.TrimSpace(b))
+	if value == "" {
+		logrus.WithField("path", r.path).
+			Warnln("vault file: token file is empty")
+		return errors.New("vault file: token file is empty")
+	}
+
+	r.mu.Lock()
+	r.modified = info.ModTime()
+	r.size = info.Size()
+	r.mu.Unlock()
+	if value != r.client.Token() {
+		r.client.SetToken(value)
+		logrus.WithField("path", r.path).
+			Debugln("vault file: token loaded")
+	}
+	return nil
+}
+
+// Renew is a no-op. The token is renewed by the Vault
+// Agent that writes the token file.
+func (r *Renewer) Renew(ctx context.Context) error {
+	return nil
+}
+
+// Revoke is a no-op. The token is owned by the Vault Agent
+// that writes the token file.
+func (r *Renewer) Revoke(ctx context.Context) error {
+	return nil
+}
+
+// TTL returns zero. The token ttl is managed by the Vault
+// Agent that writes the token file.
+func (r *Renewer) TTL() time.Duration {
+	return 0
+}
+
+// Watch returns a channel that receives a value when the
+// token file changes.
+func (r *Renewer) Watch(ctx context.Context) <-chan struct{} {
+	c := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollInterval):
+				if r.changed() {
+					select {
+					case c <- struct{}{}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return c
+}
+
+// helper function returns true if the token file has been
+// modified since it was last read.
+func (r *Renewer) changed() bool {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !info.ModTime().Equal(r.modified) || info.Size() != r.size
+}
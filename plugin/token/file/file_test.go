@@ -0,0 +1,82 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package file
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+var noContext = context.Background()
+
+func TestLogin(t *testing.T) {
+	client, _ := api.NewClient(nil)
+	client.ClearToken()
+
+	r := NewRenewer(client, "testdata/token")
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := "s.zREhsyJT79kcuGbfrKsbyo0W"
+	if got := client.Token(); got != want {
+		t.Errorf("Want token %s, got %s", want, got)
+	}
+}
+
+func TestLogin_FileError(t *testing.T) {
+	client, _ := api.NewClient(nil)
+	r := NewRenewer(client, "testdata/does-not-exist")
+	err := r.Login(noContext)
+	if _, ok := err.(*os.PathError); !ok {
+		t.Errorf("Expect PathError got %v", err)
+	}
+}
+
+func TestLogin_Empty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	ioutil.WriteFile(path, []byte("\n"), 0600)
+
+	client, _ := api.NewClient(nil)
+	client.SetToken("s.OhZm4kQxf6K45Tg0bKNQbTJD")
+
+	r := NewRenewer(client, path)
+	if err := r.Login(noContext); err == nil {
+		t.Errorf("Expect error reading empty token file")
+	}
+	if got, want := client.Token(), "s.OhZm4kQxf6K45Tg0bKNQbTJD"; got != want {
+		t.Errorf("Want existing token %s retained, got %s", want, got)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	ioutil.WriteFile(path, []byte("s.zREhsyJT79kcuGbfrKsbyo0W"), 0600)
+
+	client, _ := api.NewClient(nil)
+	r := NewRenewer(client, path)
+	r.Login(noContext)
+	if r.changed() {
+		t.Errorf("Want file unchanged")
+	}
+
+	ioutil.WriteFile(path, []byte("s.OhZm4kQxf6K45Tg0bKNQbTJD"), 0600)
+	mod := time.Now().Add(time.Minute)
+	os.Chtimes(path, mod, mod)
+	if !r.changed() {
+		t.Errorf("Want file changed")
+	}
+	r.Login(noContext)
+	if got, want := client.Token(), "s.OhZm4kQxf6K45Tg0bKNQbTJD"; got != want {
+		t.Errorf("Want token %s, got %s", want, got)
+	}
+}
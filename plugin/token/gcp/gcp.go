@@ -0,0 +1,133 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/drone/drone-vault/plugin/token"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/sirupsen/logrus"
+)
+
+// Name that identifies the auth method.
+const Name = "gcp"
+
+// Role types that identify how the JWT is obtained.
+const (
+	TypeGCE = "gce"
+	TypeIAM = "iam"
+)
+
+// Config configures the auth method.
+type Config struct {
+	Role        string `envconfig:"VAULT_GCP_ROLE"`
+	Mount       string `envconfig:"VAULT_AUTH_MOUNT_POINT"`
+	Type        string `envconfig:"VAULT_GCP_TYPE"`
+	Credentials string `envconfig:"VAULT_GCP_CREDENTIALS"`
+}
+
+func init() {
+	token.Register(Name, func(client *api.Client, prefix string) (token.Authenticator, error) {
+		config := Config{}
+		if err := token.Process(prefix, &config, "VAULT_GCP_ROLE"); err != nil {
+			return nil, err
+		}
+		return NewRenewer(client, config.Role, config.Mount, config.Type, config.Credentials), nil
+	})
+}
+
+type (
+	// Renewer renews the GCP token.
+	Renewer struct {
+		*token.Lease
+
+		client *api.Client
+
+		mount       string
+		role        string
+		kind        string
+		credentials string
+		metadata    string
+	}
+)
+
+// NewRenewer returns a new GCP token provider that logs in
+// again before the token expires. If the role type is iam,
+// the JWT is signed with the service account key file,
+// else the JWT is fetched from the metadata server.
+func NewRenewer(client *api.Client, role, mount, kind, credentials string) *Renewer {
+	if mount == "" {
+		mount = Name
+	}
+	if kind == "" {
+		kind = TypeGCE
+	}
+	return &Renewer{
+		Lease:       token.NewLease(client, 0),
+		client:      client,
+		mount:       mount,
+		role:        role,
+		kind:        kind,
+		credentials: credentials,
+		metadata:    metadataEndpoint,
+	}
+}
+
+// Login logs in to Vault and sets the client token.
+func (r *Renewer) Login(ctx context.Context) error {
+	path := fmt.Sprintf("auth/%s/login", r.mount)
+
+	logrus.WithField("type", r.kind).
+		Debugln("gcp: requesting identity token")
+
+	jwt, err := r.token(ctx)
+	if err != nil {
+		logrus.WithError(err).
+			WithField("type", r.kind).
+			Errorln("gcp: cannot request identity token")
+		return err
+	}
+
+	logrus.WithField("path", path).
+		Debugln("gcp: requesting vault token")
+
+	resp, err := r.client.Logical().WriteWithContext(ctx, path,
+		map[string]interface{}{
+			"role": r.role,
+			"jwt":  jwt,
+		})
+	if err != nil {
+		logrus.WithError(err).
+			WithField("path", path).
+			Errorln("gcp: cannot request vault token")
+		return err
+	}
+	if err := r.Set(resp); err != nil {
+		logrus.WithError(err).
+			Errorln("gcp: cannot read vault token")
+		return err
+	}
+
+	logrus.WithField("ttl", r.TTL()).
+		Debugln("gcp: token received")
+
+	return nil
+}
+
+// helper function returns the signed JWT used to login.
+func (r *Renewer) token(ctx context.Context) (string, error) {
+	switch r.kind {
+	case TypeIAM:
+		return signJWT(r.credentials, r.role, time.Now())
+	case TypeGCE:
+		return fetchJWT(ctx, r.metadata, r.role)
+	default:
+		return "", fmt.Errorf("gcp: unknown role type %q", r.kind)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package gcp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+var noContext = context.Background()
+
+func TestLogin_GCE(t *testing.T) {
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			t.Errorf("Want metadata flavor header")
+		}
+		if got, want := r.URL.Query().Get("audience"), "http://vault/dev-role"; got != want {
+			t.Errorf("Want audience %q, got %q", want, got)
+		}
+		w.Write([]byte("header.claims.signature"))
+	}))
+	defer metadata.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/gcp/login" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		in := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&in)
+		if got, want := in["jwt"], "header.claims.signature"; got != want {
+			t.Errorf("Want jwt %q, got %q", want, got)
+		}
+		if got, want := in["role"], "dev-role"; got != want {
+			t.Errorf("Want role %q, got %q", want, got)
+		}
+		data, _ := ioutil.ReadFile("testdata/token.json")
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+
+	r := NewRenewer(client, "dev-role", "", TypeGCE, "")
+	r.metadata = metadata.URL
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := "62b858f9-529c-6b26-e0b8-0457b6aacdb4"
+	if got := client.Token(); got != want {
+		t.Errorf("Want token %s, got %s", want, got)
+	}
+	if got, want := r.TTL(), 2764800*time.Second; got <= want-time.Minute || got > want {
+		t.Errorf("Want ttl %v, got %v", want, got)
+	}
+}
+
+func TestLogin_IAM(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/gcp-iam/login" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		in := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&in)
+		verifyJWT(t, in["jwt"])
+		data, _ := ioutil.ReadFile("testdata/token.json")
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+
+	r := NewRenewer(client, "dev-role", "gcp-iam", TypeIAM, "testdata/key.json")
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := "62b858f9-529c-6b26-e0b8-0457b6aacdb4"
+	if got := client.Token(); got != want {
+		t.Errorf("Want token %s, got %s", want, got)
+	}
+}
+
+func TestLogin_UnknownType(t *testing.T) {
+	r := NewRenewer(nil, "dev-role", "", "gke", "")
+	if err := r.Login(noContext); err == nil {
+		t.Errorf("Expect unknown role type error")
+	}
+}
+
+func TestLogin_RequestError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(403)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+
+	r := NewRenewer(client, "dev-role", "", TypeIAM, "testdata/key.json")
+	if err := r.Login(noContext); err == nil {
+		t.Errorf("Expect request error")
+	}
+}
+
+// helper function verifies the jwt is signed with the
+// service account key and has the expected claims.
+func verifyJWT(t *testing.T, jwt string) {
+	b, _ := ioutil.ReadFile("testdata/key.json")
+	k := new(key)
+	json.Unmarshal(b, k)
+	block, _ := pem.Decode([]byte(k.PrivateKey))
+	priv, _ := x509.ParsePKCS8PrivateKey(block.Bytes)
+	pub := &priv.(*rsa.PrivateKey).PublicKey
+
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Errorf("Want jwt with 3 parts, got %d", len(parts))
+		return
+	}
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig); err != nil {
+		t.Errorf("Want valid jwt signature, got %s", err)
+	}
+
+	header := map[string]string{}
+	raw, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	json.Unmarshal(raw, &header)
+	if got, want := header["kid"], "3f2c1e0d9b8a"; got != want {
+		t.Errorf("Want kid %q, got %q", want, got)
+	}
+
+	claims := map[string]interface{}{}
+	raw, _ = base64.RawURLEncoding.DecodeString(parts[1])
+	json.Unmarshal(raw, &claims)
+	if got, want := claims["aud"], "vault/dev-role"; got != want {
+		t.Errorf("Want aud %q, got %q", want, got)
+	}
+	if got, want := claims["sub"], "drone@drone.iam.gserviceaccount.com"; got != want {
+		t.Errorf("Want sub %q, got %q", want, got)
+	}
+}
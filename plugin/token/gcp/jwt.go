@@ -0,0 +1,127 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package gcp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// gce metadata server endpoint.
+const metadataEndpoint = "http://metadata.google.internal"
+
+// http client used to request the instance identity token
+// from the metadata server. The timeout ensures a metadata
+// server that does not respond cannot block login.
+var httpClient = &http.Client{Timeout: 5 * time.Second}
+
+// maximum lifetime of a self-signed JWT accepted by vault.
+const maxJWTExp = 15 * time.Minute
+
+// key represents the service account key file.
+type key struct {
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+}
+
+// helper function returns the audience expected by vault
+// for the role.
+func audience(role string) string {
+	return "vault/" + role
+}
+
+// helper function fetches an instance identity token from
+// the gce metadata server.
+func fetchJWT(ctx context.Context, endpoint, role string) (string, error) {
+	params := url.Values{}
+	params.Set("audience", "http://"+audience(role))
+	params.Set("format", "full")
+	path := endpoint + "/computeMetadata/v1/instance/service-accounts/default/identity?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode > 299 {
+		return "", fmt.Errorf("gcp: cannot fetch identity token: %s", res.Status)
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// helper function returns a JWT for the role, signed with
+// the service account key file.
+func signJWT(file, role string, now time.Time) (string, error) {
+	if file == "" {
+		file = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if file == "" {
+		return "", errors.New("gcp: missing service account key file")
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	k := new(key)
+	if err := json.Unmarshal(b, k); err != nil {
+		return "", err
+	}
+	block, _ := pem.Decode([]byte(k.PrivateKey))
+	if block == nil {
+		return "", errors.New("gcp: invalid service account private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	priv, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("gcp: service account private key is not an rsa key")
+	}
+
+	header, _ := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"kid": k.PrivateKeyID,
+	})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"sub": k.ClientEmail,
+		"aud": audience(role),
+		"iat": now.Unix(),
+		"exp": now.Add(maxJWTExp).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drone/drone-vault/plugin/token"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/sirupsen/logrus"
+)
+
+// Name that identifies the auth method.
+const Name = "jwt"
+
+// Config configures the auth method. Exactly one of the
+// file, command or url must be set.
+type Config struct {
+	Role    string `envconfig:"VAULT_JWT_ROLE"`
+	Mount   string `envconfig:"VAULT_AUTH_MOUNT_POINT"`
+	File    string `envconfig:"VAULT_JWT_FILE"`
+	Command string `envconfig:"VAULT_JWT_COMMAND"`
+	URL     string `envconfig:"VAULT_JWT_URL"`
+}
+
+func init() {
+	token.Register(Name, func(client *api.Client, prefix string) (token.Authenticator, error) {
+		config := Config{}
+		if err := token.Process(prefix, &config, "VAULT_JWT_ROLE"); err != nil {
+			return nil, err
+		}
+		source, err := NewSource(config.File, config.Command, config.URL)
+		if err != nil {
+			return nil, err
+		}
+		return NewRenewer(client, source, config.Role, config.Mount), nil
+	})
+}
+
+type (
+	// Renewer renews the JWT token.
+	Renewer struct {
+		*token.Lease
+
+		client *api.Client
+		source Source
+
+		mount string
+		role  string
+	}
+)
+
+// NewRenewer returns a new JWT token provider that logs in
+// again before the token expires. The JWT is read from the
+// source on every login.
+func NewRenewer(client *api.Client, source Source, role, mount string) *Renewer {
+	if mount == "" {
+		mount = Name
+	}
+	return &Renewer{
+		Lease:  token.NewLease(client, 0),
+		client: client,
+		source: source,
+		mount:  mount,
+		role:   role,
+	}
+}
+
+// Login logs in to Vault and sets the client token.
+func (r *Renewer) Login(ctx context.Context) error {
+	path := fmt.Sprintf("auth/%s/login", r.mount)
+
+	logrus.Debugln("jwt: reading token")
+
+	jwt, err := r.source.Token(ctx)
+	if err != nil {
+		logrus.WithError(err).
+			Errorln("jwt: cannot read token")
+		return err
+	}
+
+	logrus.WithField("path", path).
+		Debugln("jwt: requesting vault token")
+
+	resp, err := r.client.Logical().WriteWithContext(ctx, path,
+		map[string]interface{}{
+			"role": r.role,
+			"jwt":  jwt,
+		})
+	if err != nil {
+		logrus.WithError(err).
+			WithField("path", path).
+			Errorln("jwt: cannot request vault token")
+		return err
+	}
+	if err := r.Set(resp); err != nil {
+		logrus.WithError(err).
+			Errorln("jwt: cannot read vault token")
+		return err
+	}
+
+	logrus.WithField("ttl", r.TTL()).
+		Debugln("jwt: token received")
+
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+var noContext = context.Background()
+
+// rotatingSource returns a different token on each call.
+type rotatingSource struct {
+	tokens []string
+}
+
+func (s *rotatingSource) Token(ctx context.Context) (string, error) {
+	token := s.tokens[0]
+	s.tokens = s.tokens[1:]
+	return token, nil
+}
+
+func TestLogin(t *testing.T) {
+	var jwts []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/jwt/login" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		in := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&in)
+		if got, want := in["role"], "dev-role"; got != want {
+			t.Errorf("Want role %q, got %q", want, got)
+		}
+		jwts = append(jwts, in["jwt"])
+		data, _ := ioutil.ReadFile("testdata/token.json")
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+
+	source := &rotatingSource{tokens: []string{"first", "second"}}
+	r := NewRenewer(client, source, "dev-role", "")
+	for i := 0; i < 2; i++ {
+		if err := r.Login(noContext); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	want := "62b858f9-529c-6b26-e0b8-0457b6aacdb4"
+	if got := client.Token(); got != want {
+		t.Errorf("Want token %s, got %s", want, got)
+	}
+
+	// the jwt must be read from the source on every login.
+	if len(jwts) != 2 || jwts[0] != "first" || jwts[1] != "second" {
+		t.Errorf("Want jwt re-read on each login, got %v", jwts)
+	}
+}
+
+func TestLogin_SourceError(t *testing.T) {
+	r := NewRenewer(nil, FileSource("testdata/does-not-exist.jwt"), "dev-role", "")
+	if err := r.Login(noContext); err == nil {
+		t.Errorf("Expect source error")
+	}
+}
+
+func TestLogin_RequestError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/oidc/login" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		w.WriteHeader(400)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+
+	r := NewRenewer(client, FileSource("testdata/token.jwt"), "dev-role", "oidc")
+	if err := r.Login(noContext); err == nil {
+		t.Errorf("Expect request error")
+	}
+}
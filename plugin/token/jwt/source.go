@@ -0,0 +1,128 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+type (
+	// Source returns the JWT used to login. The JWT is
+	// requested from the source on every login, since
+	// workload identity tokens are short-lived.
+	Source interface {
+		Token(ctx context.Context) (string, error)
+	}
+
+	fileSource struct {
+		path string
+	}
+
+	commandSource struct {
+		command string
+	}
+
+	httpSource struct {
+		client *http.Client
+		url    string
+	}
+)
+
+// NewSource returns the Source for the given file path,
+// command or http endpoint. Exactly one must be set.
+func NewSource(path, command, url string) (Source, error) {
+	var sources []Source
+	if path != "" {
+		sources = append(sources, FileSource(path))
+	}
+	if command != "" {
+		sources = append(sources, CommandSource(command))
+	}
+	if url != "" {
+		sources = append(sources, HTTPSource(url))
+	}
+	if len(sources) != 1 {
+		return nil, errors.New("jwt: exactly one token file, command or url is required")
+	}
+	return sources[0], nil
+}
+
+// FileSource returns a Source that reads the JWT from the
+// file, for example a projected service account token.
+func FileSource(path string) Source {
+	return &fileSource{path: path}
+}
+
+// CommandSource returns a Source that reads the JWT from
+// the output of the shell command.
+func CommandSource(command string) Source {
+	return &commandSource{command: command}
+}
+
+// HTTPSource returns a Source that reads the JWT from the
+// http endpoint response body. The request times out so
+// that an endpoint that does not respond cannot block login.
+func HTTPSource(url string) Source {
+	return &httpSource{
+		client: &http.Client{Timeout: 5 * time.Second},
+		url:    url,
+	}
+}
+
+func (s *fileSource) Token(ctx context.Context) (string, error) {
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return "", err
+	}
+	return trim(b)
+}
+
+func (s *commandSource) Token(ctx context.Context) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", s.command)
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("jwt: command failed: %s: %s", err,
+			strings.TrimSpace(stderr.String()))
+	}
+	return trim(b)
+}
+
+func (s *httpSource) Token(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.url, nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode > 299 {
+		return "", fmt.Errorf("jwt: cannot fetch token: %s", res.Status)
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return trim(b)
+}
+
+func trim(b []byte) (string, error) {
+	s := string(bytes.TrimSpace(b))
+	if s == "" {
+		return "", errors.New("jwt: token is empty")
+	}
+	return s, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package jwt
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFileSource(t *testing.T) {
+	want, _ := ioutil.ReadFile("testdata/token.jwt")
+	got, err := FileSource("testdata/token.jwt").Token(noContext)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got != strings.TrimSpace(string(want)) {
+		t.Errorf("Want token read from file, got %q", got)
+	}
+}
+
+func TestCommandSource(t *testing.T) {
+	got, err := CommandSource("echo header.claims.signature").Token(noContext)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if want := "header.claims.signature"; got != want {
+		t.Errorf("Want token %q, got %q", want, got)
+	}
+
+	if _, err := CommandSource("exit 1").Token(noContext); err == nil {
+		t.Errorf("Expect command error")
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Write([]byte("header.claims.signature\n"))
+			return
+		}
+		w.WriteHeader(404)
+	}))
+	defer ts.Close()
+
+	got, err := HTTPSource(ts.URL + "/token").Token(noContext)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if want := "header.claims.signature"; got != want {
+		t.Errorf("Want token %q, got %q", want, got)
+	}
+
+	if _, err := HTTPSource(ts.URL + "/missing").Token(noContext); err == nil {
+		t.Errorf("Expect request error")
+	}
+}
+
+func TestNewSource(t *testing.T) {
+	if _, err := NewSource("", "", ""); err == nil {
+		t.Errorf("Expect error when no source is configured")
+	}
+	if _, err := NewSource("testdata/token.jwt", "cat testdata/token.jwt", ""); err == nil {
+		t.Errorf("Expect error when multiple sources are configured")
+	}
+	if _, err := NewSource("", "", "http://localhost/token"); err != nil {
+		t.Error(err)
+	}
+}
@@ -8,8 +8,12 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"sync"
 	"time"
 
+	"github.com/drone/drone-vault/plugin/token"
+
 	"github.com/hashicorp/vault/api"
 	"github.com/sirupsen/logrus"
 )
@@ -20,98 +24,163 @@ const Name = "kubernetes"
 // kubernetes token file path.
 const defaultPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
 
-type (
-	// kubernetes authorization provider request.
-	request struct {
-		Jwt  string `json:"jwt"`
-		Role string `json:"role"`
-	}
+// interval at which the token file is checked for changes.
+const pollInterval = 5 * time.Second
+
+// Config configures the auth method. The account token is
+// read from the path on every login. If watch is true, a
+// new Vault token is requested when the account token file
+// changes, for example when a projected service account
+// token is rotated.
+type Config struct {
+	Role  string `envconfig:"VAULT_KUBERNETES_ROLE"`
+	Mount string `envconfig:"VAULT_AUTH_MOUNT_POINT"`
+	Path  string `envconfig:"VAULT_KUBERNETES_TOKEN_PATH"`
+	Watch bool   `envconfig:"VAULT_KUBERNETES_TOKEN_WATCH"`
+}
 
-	// kubernetes authorization provider response.
-	response struct {
-		Auth struct {
-			Token string `json:"client_token"`
-			Lease int    `json:"lease_duration"`
+func init() {
+	token.Register(Name, func(client *api.Client, prefix string) (token.Authenticator, error) {
+		config := Config{}
+		if err := token.Process(prefix, &config, "VAULT_KUBERNETES_ROLE"); err != nil {
+			return nil, err
 		}
-	}
+		return NewRenewer(client, config), nil
+	})
+}
 
+type (
 	// Renewer renews the Kubernetes token.
 	Renewer struct {
+		*token.Lease
+
 		client *api.Client
 
-		address string
-		mount   string
-		path    string
-		role    string
+		mount string
+		path  string
+		role  string
+		watch bool
+
+		mu       sync.Mutex
+		modified time.Time
+		size     int64
 	}
 )
 
 // NewRenewer returns a new Kubernetes token provider
-// that renews the token on expiration.
-func NewRenewer(client *api.Client, address, role, mount string) *Renewer {
+// that authenticates with the service account token.
+func NewRenewer(client *api.Client, config Config) *Renewer {
+	if config.Mount == "" {
+		config.Mount = Name
+	}
+	if config.Path == "" {
+		config.Path = defaultPath
+	}
 	return &Renewer{
-		address: address,
-		client:  client,
-		mount:   mount,
-		role:    role,
-		path:    defaultPath,
+		Lease:  token.NewLease(client, 0),
+		client: client,
+		mount:  config.Mount,
+		role:   config.Role,
+		path:   config.Path,
+		watch:  config.Watch,
 	}
 }
 
-// Renew renews the Vault token.
-func (r *Renewer) Renew(ctx context.Context) error {
-	// create the vault endpoint address.
-	endpoint := fmt.Sprintf("%s/v1/auth/%s/login", r.address, r.mount)
+// Login requests a new Vault token.
+func (r *Renewer) Login(ctx context.Context) error {
+	path := fmt.Sprintf("auth/%s/login", r.mount)
 
 	logrus.WithField("path", r.path).
 		Debugln("kubernetes: reading account token")
 
 	// reads the jwt token mounted inside the container.
-	b, err := ioutil.ReadFile(r.path)
+	// the token is read on every login, since projected
+	// service account tokens are rotated.
+	info, err := os.Stat(r.path)
 	if err != nil {
 		logrus.WithError(err).
 			WithField("path", r.path).
 			Errorln("kubernetes: cannot read account token")
 		return err
 	}
-
-	res := &response{}
-	req := &request{
-		Jwt:  string(b),
-		Role: r.role,
+	b, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		logrus.WithError(err).
+			WithField("path", r.path).
+			Errorln("kubernetes: cannot read account token")
+		return err
 	}
+	r.mu.Lock()
+	r.modified = info.ModTime()
+	r.size = info.Size()
+	r.mu.Unlock()
 
-	logrus.WithField("endpoint", endpoint).
+	logrus.WithField("path", path).
 		Debugln("kubernetes: requesting vault token")
 
-	err = post(endpoint, req, res)
+	// the login request is sent with the vault client, so
+	// that it uses the configured tls, namespace and retry
+	// settings.
+	resp, err := r.client.Logical().WriteWithContext(ctx, path,
+		map[string]interface{}{
+			"jwt":  string(b),
+			"role": r.role,
+		})
 	if err != nil {
 		logrus.WithError(err).
-			WithField("endpoint", endpoint).
+			WithField("path", path).
 			Errorln("kubernetes: cannot request vault token")
 		return err
 	}
+	if err := r.Set(resp); err != nil {
+		logrus.WithError(err).
+			Errorln("kubernetes: cannot read vault token")
+		return err
+	}
 
-	r.client.SetToken(res.Auth.Token)
-	ttl := time.Duration(res.Auth.Lease) * time.Second
-
-	logrus.WithField("ttl", ttl).
+	logrus.WithField("ttl", r.TTL()).
 		Debugln("kubernetes: token received")
 
 	return nil
 }
 
-// Run performs token renewal at scheduled intervals.
-func (r *Renewer) Run(ctx context.Context, renew time.Duration) error {
-	if renew == 0 {
-		renew = time.Hour
+// Watch returns a channel that receives a value when the
+// account token file changes. If watching is disabled, the
+// channel never receives a value.
+func (r *Renewer) Watch(ctx context.Context) <-chan struct{} {
+	c := make(chan struct{})
+	if !r.watch {
+		return c
 	}
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(renew):
-			r.Renew(ctx)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollInterval):
+				if r.changed() {
+					logrus.WithField("path", r.path).
+						Debugln("kubernetes: account token changed")
+					select {
+					case c <- struct{}{}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
 		}
+	}()
+	return c
+}
+
+// helper function returns true if the account token file
+// has been modified since it was last read.
+func (r *Renewer) changed() bool {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false
 	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !info.ModTime().Equal(r.modified) || info.Size() != r.size
 }
@@ -6,11 +6,14 @@ package kubernetes
 
 import (
 	"context"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/api"
 )
@@ -27,11 +30,14 @@ func TestLoad(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	client, _ := api.NewClient(nil)
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
 
-	r := NewRenewer(client, ts.URL, "dev-role", "kubernetes")
-	r.path = "testdata/token.jwt"
-	err := r.Renew(noContext)
+	r := NewRenewer(client, Config{
+		Role:  "dev-role",
+		Mount: "kubernetes",
+		Path:  "testdata/token.jwt",
+	})
+	err := r.Login(noContext)
 	if err != nil {
 		t.Error(err)
 	}
@@ -44,9 +50,13 @@ func TestLoad(t *testing.T) {
 }
 
 func TestLoad_FileError(t *testing.T) {
-	r := NewRenewer(nil, "http://localhost", "dev-role", "kubernetes")
-	r.path = "testdata/does-not-exist.jwt"
-	err := r.Renew(noContext)
+	client, _ := api.NewClient(nil)
+	r := NewRenewer(client, Config{
+		Role:  "dev-role",
+		Mount: "kubernetes",
+		Path:  "testdata/does-not-exist.jwt",
+	})
+	err := r.Login(noContext)
 	if _, ok := err.(*os.PathError); !ok {
 		t.Errorf("Expect PathError got %v", err)
 	}
@@ -61,10 +71,111 @@ func TestLoad_RequestError(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	r := NewRenewer(nil, ts.URL, "dev-role", "kubernetes")
-	r.path = "testdata/token.jwt"
-	err := r.Renew(noContext)
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	r := NewRenewer(client, Config{
+		Role:  "dev-role",
+		Mount: "kubernetes",
+		Path:  "testdata/token.jwt",
+	})
+	err := r.Login(noContext)
 	if err == nil {
 		t.Errorf("Expect request error")
 	}
 }
+
+func TestChanged(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadFile("testdata/token.json")
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "token")
+	data, _ := ioutil.ReadFile("testdata/token.jwt")
+	ioutil.WriteFile(path, data, 0600)
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	r := NewRenewer(client, Config{
+		Role:  "dev-role",
+		Mount: "kubernetes",
+		Path:  path,
+		Watch: true,
+	})
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+		return
+	}
+	if r.changed() {
+		t.Errorf("Want file unchanged")
+	}
+
+	// the projected token is rotated.
+	ioutil.WriteFile(path, append(data, '\n'), 0600)
+	mod := time.Now().Add(time.Minute)
+	os.Chtimes(path, mod, mod)
+	if !r.changed() {
+		t.Errorf("Want file changed")
+	}
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+	}
+	if r.changed() {
+		t.Errorf("Want file unchanged after login")
+	}
+}
+
+// Test the login request is sent with the vault client, and
+// uses the client tls configuration.
+func TestLoad_TLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/kubernetes/login" {
+			t.Errorf("Invalid path")
+		}
+		in := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&in)
+		if got, want := in["role"], "dev-role"; got != want {
+			t.Errorf("Want role %q, got %q", want, got)
+		}
+		if in["jwt"] == "" {
+			t.Errorf("Want account token")
+		}
+		data, _ := ioutil.ReadFile("testdata/token.json")
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	config := api.DefaultConfig()
+	config.Address = ts.URL
+	config.HttpClient = ts.Client()
+	client, _ := api.NewClient(config)
+
+	r := NewRenewer(client, Config{
+		Role: "dev-role",
+		Path: "testdata/token.jwt",
+	})
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+	}
+	if got, want := client.Token(), "62b858f9-529c-6b26-e0b8-0457b6aacdb4"; got != want {
+		t.Errorf("Want token %s, got %s", want, got)
+	}
+}
+
+func TestLoad_Canceled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Want request canceled")
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(noContext)
+	cancel()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	r := NewRenewer(client, Config{
+		Role: "dev-role",
+		Path: "testdata/token.jwt",
+	})
+	if err := r.Login(ctx); err == nil {
+		t.Errorf("Expect context canceled error")
+	}
+}
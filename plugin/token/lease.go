@@ -0,0 +1,178 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package token
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+var (
+	// ErrNoToken is returned when there is no client token,
+	// or the login response does not include a token.
+	ErrNoToken = errors.New("vault: expected a client token")
+
+	// ErrTTLCapped is returned when the token is renewed
+	// for less than the requested increment, because the
+	// token is approaching its max ttl.
+	ErrTTLCapped = errors.New("vault: token could not be renewed for desired ttl")
+
+	// ErrNotRenewable is returned when the token cannot be
+	// renewed, and a new token must be requested.
+	ErrNotRenewable = errors.New("vault: token is not renewable")
+)
+
+// Lease tracks the lease of the client token and implements
+// token renewal, revocation and ttl. It is embedded by auth
+// methods that obtain a token from a login request.
+type Lease struct {
+	client    *api.Client
+	increment time.Duration
+
+	mu        sync.Mutex
+	expires   time.Time
+	duration  time.Duration
+	renewable bool
+}
+
+// NewLease returns a new Lease for the client. The token
+// is renewed for the increment, or for the default ttl of
+// the token if the increment is zero.
+func NewLease(client *api.Client, increment time.Duration) *Lease {
+	return &Lease{
+		client:    client,
+		increment: increment,
+		renewable: true,
+	}
+}
+
+// Set sets the client token from the login response and
+// records the token lease.
+func (l *Lease) Set(secret *api.Secret) error {
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return ErrNoToken
+	}
+	l.client.SetToken(secret.Auth.ClientToken)
+	l.update(secret.Auth.LeaseDuration, secret.Auth.Renewable, true)
+	return nil
+}
+
+// Lookup records the lease of the client token, using the
+// token lookup-self api. It is used when the token was not
+// obtained from a login request.
+func (l *Lease) Lookup(ctx context.Context) error {
+	if l.client.Token() == "" {
+		return ErrNoToken
+	}
+	secret, err := l.client.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	ttl, err := secret.TokenTTL()
+	if err != nil {
+		return err
+	}
+	renewable, err := secret.TokenIsRenewable()
+	if err != nil {
+		return err
+	}
+	l.update(int(ttl/time.Second), renewable, true)
+	return nil
+}
+
+// Renew renews the client token. If the token is renewed
+// for less than the requested increment or, if no increment
+// is set, for less than the lease duration, the token has
+// reached its max ttl and ErrTTLCapped is returned.
+func (l *Lease) Renew(ctx context.Context) error {
+	if l.client.Token() == "" {
+		return ErrNoToken
+	}
+	if !l.Renewable() {
+		return ErrNotRenewable
+	}
+	incr := int(l.increment / time.Second)
+	secret, err := l.client.Auth().Token().RenewSelfWithContext(ctx, incr)
+	if err != nil {
+		return err
+	}
+	if secret == nil || secret.Auth == nil {
+		return ErrNoToken
+	}
+	want := incr
+	if want == 0 {
+		want = int(l.Duration() / time.Second)
+	}
+	l.update(secret.Auth.LeaseDuration, secret.Auth.Renewable, false)
+	if want > 0 && secret.Auth.LeaseDuration < want {
+		return ErrTTLCapped
+	}
+	return nil
+}
+
+// Revoke revokes the client token.
+func (l *Lease) Revoke(ctx context.Context) error {
+	if l.client.Token() == "" {
+		return nil
+	}
+	err := l.client.Auth().Token().RevokeSelfWithContext(ctx, "")
+	if err != nil {
+		return err
+	}
+	l.client.ClearToken()
+	l.update(0, false, true)
+	return nil
+}
+
+// TTL returns the remaining time to live of the client
+// token, or zero if unknown.
+func (l *Lease) TTL() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.expires.IsZero() {
+		return 0
+	}
+	if ttl := time.Until(l.expires); ttl > 0 {
+		return ttl
+	}
+	return 0
+}
+
+// Renewable returns true if the client token is renewable.
+// A token is assumed renewable until the lease is known.
+func (l *Lease) Renewable() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.renewable
+}
+
+// Duration returns the lease duration granted at login.
+func (l *Lease) Duration() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.duration
+}
+
+// helper function records the lease duration in seconds.
+// If granted is true, the lease duration is recorded as the
+// duration granted at login.
+func (l *Lease) update(seconds int, renewable, granted bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.renewable = renewable
+	ttl := time.Duration(seconds) * time.Second
+	if granted {
+		l.duration = ttl
+	}
+	if seconds <= 0 {
+		l.expires = time.Time{}
+		return
+	}
+	l.expires = time.Now().Add(ttl)
+}
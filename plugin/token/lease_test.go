@@ -0,0 +1,159 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package token
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestLease(t *testing.T) {
+	client, _ := api.NewClient(nil)
+	l := NewLease(client, 0)
+	if ttl := l.TTL(); ttl != 0 {
+		t.Errorf("Want unknown ttl, got %v", ttl)
+	}
+
+	err := l.Set(&api.Secret{
+		Auth: &api.SecretAuth{
+			ClientToken:   "8609694a-cdbc-db9b-d345-e782dbb562ed",
+			LeaseDuration: 3600,
+		},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := client.Token(), "8609694a-cdbc-db9b-d345-e782dbb562ed"; got != want {
+		t.Errorf("Want token %s, got %s", want, got)
+	}
+	if ttl := l.TTL(); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("Want ttl of one hour, got %v", ttl)
+	}
+
+	if err := l.Set(&api.Secret{}); err != ErrNoToken {
+		t.Errorf("Want no token error, got %v", err)
+	}
+}
+
+func TestLease_RenewCapped(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/token/renew-self" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		out, _ := ioutil.ReadFile("testdata/renew.json")
+		w.Write(out)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	client.SetToken("8609694a-cdbc-db9b-d345-e782dbb562ed")
+
+	// the token is renewed for one hour, less than the
+	// requested increment.
+	l := NewLease(client, 2*time.Hour)
+	if err := l.Renew(noContext); err != ErrTTLCapped {
+		t.Errorf("Want ttl capped error, got %v", err)
+	}
+
+	l = NewLease(client, time.Hour)
+	if err := l.Renew(noContext); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLease_Revoke(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/token/revoke-self" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		w.WriteHeader(204)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	client.SetToken("8609694a-cdbc-db9b-d345-e782dbb562ed")
+
+	l := NewLease(client, 0)
+	if err := l.Revoke(noContext); err != nil {
+		t.Error(err)
+	}
+	if client.Token() != "" {
+		t.Errorf("Want token cleared")
+	}
+}
+
+func TestLease_Lookup(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/token/lookup-self" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		out, _ := ioutil.ReadFile("testdata/lookup.json")
+		w.Write(out)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	client.SetToken("8609694a-cdbc-db9b-d345-e782dbb562ed")
+
+	l := NewLease(client, 0)
+	if err := l.Lookup(noContext); err != nil {
+		t.Error(err)
+		return
+	}
+	if ttl := l.TTL(); ttl <= 29*time.Minute || ttl > 30*time.Minute {
+		t.Errorf("Want ttl of 30 minutes, got %v", ttl)
+	}
+	if !l.Renewable() {
+		t.Errorf("Want token renewable")
+	}
+}
+
+// Test a token that is not renewable is not renewed.
+func TestLease_NotRenewable(t *testing.T) {
+	client, _ := api.NewClient(nil)
+	l := NewLease(client, 0)
+	l.Set(&api.Secret{
+		Auth: &api.SecretAuth{
+			ClientToken:   "8609694a-cdbc-db9b-d345-e782dbb562ed",
+			LeaseDuration: 3600,
+			Renewable:     false,
+		},
+	})
+	if err := l.Renew(noContext); err != ErrNotRenewable {
+		t.Errorf("Want not renewable error, got %v", err)
+	}
+}
+
+// Test the token is capped when renewed for less than the
+// lease duration granted at login.
+func TestLease_RenewCappedDuration(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out, _ := ioutil.ReadFile("testdata/renew.json")
+		w.Write(out)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	l := NewLease(client, 0)
+	l.Set(&api.Secret{
+		Auth: &api.SecretAuth{
+			ClientToken:   "8609694a-cdbc-db9b-d345-e782dbb562ed",
+			LeaseDuration: 7200,
+			Renewable:     true,
+		},
+	})
+	if err := l.Renew(noContext); err != ErrTTLCapped {
+		t.Errorf("Want ttl capped error, got %v", err)
+	}
+	if ttl := l.TTL(); ttl > time.Hour {
+		t.Errorf("Want ttl updated to renewed lease, got %v", ttl)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package token
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/drone/drone-vault/plugin/health"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/sirupsen/logrus"
+)
+
+// default interval between token checks.
+const defaultCheck = 5 * time.Minute
+
+// Info describes the client token, as reported by the
+// token lookup-self api.
+type Info struct {
+	Policies  []string
+	TTL       time.Duration
+	Renewable bool
+	EntityID  string
+}
+
+// Monitor periodically checks that the client token is
+// valid, using the token lookup-self api, and reports the
+// result through its health status.
+type Monitor struct {
+	client   *api.Client
+	interval time.Duration
+
+	mu      sync.Mutex
+	info    *Info
+	err     error
+	updated time.Time
+}
+
+// NewMonitor returns a new token monitor.
+func NewMonitor(client *api.Client, interval time.Duration) *Monitor {
+	if interval == 0 {
+		interval = defaultCheck
+	}
+	return &Monitor{
+		client:   client,
+		interval: interval,
+	}
+}
+
+// Check looks up the client token, and records the result.
+func (m *Monitor) Check(ctx context.Context) (*Info, error) {
+	info, err := m.lookup(ctx)
+
+	m.mu.Lock()
+	m.info = info
+	m.err = err
+	m.updated = time.Now()
+	m.mu.Unlock()
+
+	if err != nil {
+		logrus.WithError(err).
+			Errorln("vault: token check failed, secrets cannot be read")
+		return nil, err
+	}
+	logrus.WithField("policies", info.Policies).
+		WithField("ttl", info.TTL).
+		WithField("renewable", info.Renewable).
+		WithField("entity", info.EntityID).
+		Infoln("vault: token check succeeded")
+	return info, nil
+}
+
+// Run checks the client token at scheduled intervals.
+func (m *Monitor) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.interval):
+			m.Check(ctx)
+		}
+	}
+}
+
+// Health returns the result of the last token check.
+func (m *Monitor) Health() health.Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := health.Status{
+		Name:    "token-check",
+		Healthy: m.err == nil,
+		Updated: m.updated,
+	}
+	if m.err != nil {
+		status.Error = m.err.Error()
+	}
+	if m.info != nil {
+		status.Details = map[string]interface{}{
+			"policies":  m.info.Policies,
+			"ttl":       int64(m.info.TTL / time.Second),
+			"renewable": m.info.Renewable,
+			"entity":    m.info.EntityID,
+		}
+	}
+	return status
+}
+
+// helper function looks up the client token.
+func (m *Monitor) lookup(ctx context.Context) (*Info, error) {
+	if m.client.Token() == "" {
+		return nil, ErrNoToken
+	}
+	secret, err := m.client.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("vault: token lookup returned no data")
+	}
+	info := new(Info)
+	if info.Policies, err = secret.TokenPolicies(); err != nil {
+		return nil, err
+	}
+	if info.TTL, err = secret.TokenTTL(); err != nil {
+		return nil, err
+	}
+	if info.Renewable, err = secret.TokenIsRenewable(); err != nil {
+		return nil, err
+	}
+	info.EntityID, _ = secret.Data["entity_id"].(string)
+	return info, nil
+}
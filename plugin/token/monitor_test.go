@@ -0,0 +1,68 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package token
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/vault/api"
+)
+
+func TestMonitor(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/token/lookup-self" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		out, _ := ioutil.ReadFile("testdata/lookup.json")
+		w.Write(out)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	client.SetToken("8609694a-cdbc-db9b-d345-e782dbb562ed")
+
+	m := NewMonitor(client, time.Minute)
+	info, err := m.Check(noContext)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	want := &Info{
+		Policies:  []string{"default", "testgroup2-policy", "dev-group-policy"},
+		TTL:       30 * time.Minute,
+		Renewable: true,
+		EntityID:  "7d2e3179-f69b-450c-7179-ac8ee8bd8ca9",
+	}
+	if diff := cmp.Diff(want, info); diff != "" {
+		t.Errorf(diff)
+	}
+	if status := m.Health(); !status.Healthy || status.Details["entity"] != want.EntityID {
+		t.Errorf("Want healthy status, got %+v", status)
+	}
+}
+
+func TestMonitor_Invalid(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(403)
+		w.Write([]byte(`{"errors":["permission denied"]}`))
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL, MaxRetries: 0})
+	client.SetToken("8609694a-cdbc-db9b-d345-e782dbb562ed")
+
+	m := NewMonitor(client, time.Minute)
+	if _, err := m.Check(noContext); err == nil {
+		t.Errorf("Want token check error")
+	}
+	if status := m.Health(); status.Healthy || status.Error == "" {
+		t.Errorf("Want unhealthy status, got %+v", status)
+	}
+}
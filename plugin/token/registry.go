@@ -0,0 +1,65 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package token
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// Factory returns a new Authenticator for the client. The
+// auth method reads its configuration from the environment.
+// If the prefix is set, variables are only read with the
+// prefix, for example PROD_VAULT_APPROLE_ID, and the factory
+// returns an error if a required variable is unset.
+type Factory func(client *api.Client, prefix string) (Authenticator, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register registers the auth method factory by name, which
+// is the value of VAULT_AUTH_TYPE used to select the method.
+// Register panics if the name is already registered.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		panic("token: auth method registered twice: " + name)
+	}
+	registry[name] = factory
+}
+
+// Lookup returns the registered auth method factory. If
+// the name is empty, the static token auth method is
+// returned.
+func Lookup(name string) (Factory, error) {
+	if name == "" {
+		name = Name
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("token: unknown auth method %q", name)
+	}
+	return factory, nil
+}
+
+// Names returns the names of the registered auth methods.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package token
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestLookup(t *testing.T) {
+	factory, err := Lookup("")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	client, _ := api.NewClient(nil)
+	auth, err := factory(client, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, ok := auth.(*static); !ok {
+		t.Errorf("Want static token auth method by default")
+	}
+
+	if _, err := Lookup("unknown"); err == nil {
+		t.Errorf("Want error for unknown auth method")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Want panic registering auth method twice")
+		}
+	}()
+	Register(Name, nil)
+}
+
+// Test the auth method reads prefixed variables for a named
+// vault backend.
+func TestLookup_Prefix(t *testing.T) {
+	t.Setenv("PROD_VAULT_TOKEN_REVOKE", "true")
+
+	factory, _ := Lookup(Name)
+	client, _ := api.NewClient(nil)
+	client.SetToken("s.Mt6f1IPyUMEmjMhAE1t4uGGS")
+	auth, err := factory(client, "PROD")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !auth.(*static).revoke {
+		t.Errorf("Want prefixed variable read")
+	}
+	auth, _ = factory(client, "")
+	if auth.(*static).revoke {
+		t.Errorf("Want prefixed variable ignored without prefix")
+	}
+}
+
+// Test the static token auth method of a named vault backend
+// requires a token.
+func TestLookup_PrefixMissingToken(t *testing.T) {
+	factory, _ := Lookup(Name)
+	client, _ := api.NewClient(nil)
+	client.ClearToken()
+	if _, err := factory(client, "PROD"); err == nil {
+		t.Errorf("Want error for missing token")
+	}
+}
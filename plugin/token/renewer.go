@@ -6,33 +6,103 @@ package token
 
 import (
 	"context"
+	"math/rand"
+	"sync"
 	"time"
 
-	"github.com/hashicorp/vault/api"
+	"github.com/drone/drone-vault/plugin/health"
+
 	"github.com/sirupsen/logrus"
 )
 
-// Renewer implements token renewal.
+// default token renewal interval.
+const defaultRenew = time.Hour
+
+// minimum token renewal interval.
+const minRenew = time.Second
+
+// initial and maximum interval between retries after a
+// failed login or renewal.
+const (
+	minBackoff = time.Second
+	maxBackoff = 5 * time.Minute
+)
+
+// Renewer manages the token lifecycle for an auth method.
+// The token is renewed at two-thirds of its remaining ttl,
+// and at least once per renewal interval. A new token is
+// requested when the token cannot be renewed, or before it
+// expires once it reaches its max ttl. Failed requests are
+// retried with capped exponential backoff.
 type Renewer struct {
-	client *api.Client
-	ttl    time.Duration
-	renew  time.Duration
+	auth  Authenticator
+	renew time.Duration
+
+	// relogin is true if the token reached its max ttl, or
+	// the last login failed, and a new token is requested
+	// at the next interval.
+	relogin bool
+
+	mu       sync.Mutex
+	failures int
+	err      error
+	updated  time.Time
 }
 
 // NewRenewer returns a new token renewer.
-func NewRenewer(client *api.Client, ttl, renew time.Duration) *Renewer {
+func NewRenewer(auth Authenticator, renew time.Duration) *Renewer {
+	if renew == 0 {
+		renew = defaultRenew
+	}
 	return &Renewer{
-		client: client,
-		ttl:    ttl,
-		renew:  renew,
+		auth:  auth,
+		renew: renew,
+	}
+}
+
+// Login requests the initial token. If the request fails,
+// it is retried by Run.
+func (r *Renewer) Login(ctx context.Context) error {
+	return r.login(ctx)
+}
+
+// Revoke revokes the token. It is called on shutdown, after
+// Run returns, so that the token cannot be used once the
+// plugin stops.
+func (r *Renewer) Revoke(ctx context.Context) error {
+	err := r.auth.Revoke(ctx)
+	if err != nil {
+		logrus.WithError(err).
+			Warnln("vault: cannot revoke token")
+	}
+	return err
+}
+
+// Health returns the token health status. The token is
+// unhealthy if the last login or renewal failed.
+func (r *Renewer) Health() health.Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := health.Status{
+		Name:     "token",
+		Healthy:  r.failures == 0,
+		Failures: r.failures,
+		Updated:  r.updated,
+		Details: map[string]interface{}{
+			"ttl": int64(r.auth.TTL() / time.Second),
+		},
+	}
+	if r.err != nil {
+		status.Error = r.err.Error()
 	}
+	return status
 }
 
 // Run performs token renewal at scheduled intervals.
 func (r *Renewer) Run(ctx context.Context) error {
-	if r.renew == 0 || r.ttl == 0 {
-		logrus.Debugf("vault: token refreshing disabled")
-		return nil
+	var changed <-chan struct{}
+	if w, ok := r.auth.(Watcher); ok {
+		changed = w.Watch(ctx)
 	}
 
 	logrus.Infof("vault: token renewal enabled: %v interval", r.renew)
@@ -41,21 +111,107 @@ func (r *Renewer) Run(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(r.renew):
-			r.refresh()
+		case <-changed:
+			r.login(ctx)
+		case <-time.After(r.interval()):
+			r.refresh(ctx)
 		}
 	}
 }
 
-func (r *Renewer) refresh() error {
-	incr := int(r.ttl / time.Second)
+// helper function returns the time until the next renewal.
+// the token is renewed before it expires, even if the
+// interval exceeds the token ttl. up to 10% jitter is
+// applied to avoid renewing many tokens at the same time.
+func (r *Renewer) interval() time.Duration {
+	if n := r.failed(); n > 0 {
+		return backoff(n)
+	}
+	wait := r.renew
+	if ttl := r.auth.TTL(); ttl > 0 && ttl*2/3 < wait {
+		wait = ttl * 2 / 3
+	}
+	if jitter := int64(wait / 10); jitter > 0 {
+		wait -= time.Duration(rand.Int63n(jitter))
+	}
+	if wait < minRenew {
+		wait = minRenew
+	}
+	return wait
+}
+
+// helper function renews the token, and requests a new
+// token if the token cannot be renewed.
+func (r *Renewer) refresh(ctx context.Context) error {
+	if r.relogin {
+		logrus.Infoln("vault: token reached max ttl, requesting new token")
+		return r.login(ctx)
+	}
+	err := r.auth.Renew(ctx)
+	switch {
+	case err == nil:
+		r.record(nil)
+		return nil
+	case err == ErrTTLCapped && r.auth.TTL() > 0:
+		// the token is still valid for the remaining ttl,
+		// and is replaced before it expires.
+		logrus.WithField("ttl", r.auth.TTL()).
+			Infoln("vault: token reached max ttl, new token will be requested before expiry")
+		r.relogin = true
+		r.record(nil)
+		return nil
+	}
+	logrus.WithError(err).
+		Infoln("vault: token could not be renewed, requesting new token")
+	return r.login(ctx)
+}
 
-	logrus.Debugf("vault: refreshing token: increment %v", r.ttl)
-	_, err := r.client.Auth().Token().RenewSelf(incr)
+func (r *Renewer) login(ctx context.Context) error {
+	err := r.auth.Login(ctx)
+	r.record(err)
 	if err != nil {
-		logrus.Errorf("vault: refreshing token failed: %s", err)
+		r.relogin = true
+		logrus.WithError(err).
+			WithField("retry", r.failed()).
+			Errorln("vault: cannot request new token")
 		return err
 	}
-	logrus.Debugf("vault: refreshing token succeeded")
+	r.relogin = false
 	return nil
 }
+
+// helper function records the result of the last login
+// or renewal.
+func (r *Renewer) record(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.failures++
+	} else {
+		r.failures = 0
+	}
+	r.err = err
+	r.updated = time.Now()
+}
+
+// helper function returns the number of consecutive
+// failed requests.
+func (r *Renewer) failed() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.failures
+}
+
+// helper function returns the time until the next retry
+// after n consecutive failures. the interval doubles with
+// each failure, up to the maximum, and is randomized to
+// avoid many instances retrying at the same time.
+func backoff(n int) time.Duration {
+	wait := maxBackoff
+	if n < 32 {
+		if d := minBackoff << uint(n-1); d < maxBackoff {
+			wait = d
+		}
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)))
+}
@@ -5,6 +5,8 @@
 package token
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -14,6 +16,8 @@ import (
 	"github.com/hashicorp/vault/api"
 )
 
+var noContext = context.Background()
+
 func TestRenew(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		out, _ := ioutil.ReadFile("testdata/renew.json")
@@ -27,8 +31,8 @@ func TestRenew(t *testing.T) {
 	})
 	client.SetToken("8609694a-cdbc-db9b-d345-e782dbb562ed")
 
-	r := NewRenewer(client, time.Minute, time.Minute)
-	err := r.refresh()
+	r := NewRenewer(NewStatic(client, time.Minute, false), time.Minute)
+	err := r.refresh(noContext)
 	if err != nil {
 		t.Error(err)
 	}
@@ -44,10 +48,163 @@ func TestRenewErr(t *testing.T) {
 		Address:    ts.URL,
 		MaxRetries: 1,
 	})
+	client.SetToken("8609694a-cdbc-db9b-d345-e782dbb562ed")
 
-	r := NewRenewer(client, time.Minute, time.Minute)
-	err := r.refresh()
+	a := NewStatic(client, time.Minute, false)
+	err := a.Renew(noContext)
 	if err == nil {
 		t.Errorf("Want error refreshing token")
 	}
 }
+
+// fakeAuth records calls to the auth method.
+type fakeAuth struct {
+	renewErr error
+	loginErr error
+	logins   int
+	renews   int
+	ttl      time.Duration
+}
+
+func (a *fakeAuth) Login(ctx context.Context) error  { a.logins++; return a.loginErr }
+func (a *fakeAuth) Renew(ctx context.Context) error  { a.renews++; return a.renewErr }
+func (a *fakeAuth) Revoke(ctx context.Context) error { return nil }
+func (a *fakeAuth) TTL() time.Duration               { return a.ttl }
+
+// Test a new token is requested when renewal fails.
+func TestRefresh_Login(t *testing.T) {
+	auth := &fakeAuth{renewErr: errors.New("permission denied")}
+	r := NewRenewer(auth, time.Minute)
+	if err := r.refresh(noContext); err != nil {
+		t.Error(err)
+	}
+	if auth.renews != 1 || auth.logins != 1 {
+		t.Errorf("Want 1 renew and 1 login, got %d renews and %d logins", auth.renews, auth.logins)
+	}
+}
+
+// Test a new token is requested at the next interval when
+// the token reaches its max ttl.
+func TestRefresh_Capped(t *testing.T) {
+	auth := &fakeAuth{renewErr: ErrTTLCapped, ttl: time.Minute}
+	r := NewRenewer(auth, time.Minute)
+	if err := r.refresh(noContext); err != nil {
+		t.Error(err)
+	}
+	if auth.renews != 1 || auth.logins != 0 {
+		t.Errorf("Want 1 renew and 0 logins, got %d renews and %d logins", auth.renews, auth.logins)
+	}
+	if err := r.refresh(noContext); err != nil {
+		t.Error(err)
+	}
+	if auth.renews != 1 || auth.logins != 1 {
+		t.Errorf("Want 1 renew and 1 login, got %d renews and %d logins", auth.renews, auth.logins)
+	}
+	if r.relogin {
+		t.Errorf("Want token renewed at next interval")
+	}
+}
+
+func TestInterval(t *testing.T) {
+	tests := []struct {
+		renew time.Duration
+		ttl   time.Duration
+		want  time.Duration
+	}{
+		{renew: time.Hour, ttl: 0, want: time.Hour},
+		{renew: time.Hour, ttl: 72 * time.Hour, want: time.Hour},
+		{renew: time.Hour, ttl: 30 * time.Minute, want: 20 * time.Minute},
+		{renew: 0, ttl: 0, want: defaultRenew},
+		{renew: time.Hour, ttl: time.Second, want: minRenew},
+	}
+	for _, test := range tests {
+		r := NewRenewer(&fakeAuth{ttl: test.ttl}, test.renew)
+		if got := r.interval(); got > test.want || got < test.want*9/10 {
+			t.Errorf("Want interval %v with jitter, got %v", test.want, got)
+		}
+	}
+}
+
+// Test failed logins are retried with backoff, and are
+// reported through the health status.
+func TestRefresh_Backoff(t *testing.T) {
+	auth := &fakeAuth{loginErr: errors.New("connection refused")}
+	r := NewRenewer(auth, time.Hour)
+	if err := r.Login(noContext); err == nil {
+		t.Errorf("Want login error")
+	}
+	if got := r.interval(); got > time.Second {
+		t.Errorf("Want retry within 1s, got %v", got)
+	}
+	status := r.Health()
+	if status.Healthy || status.Failures != 1 || status.Error != "connection refused" {
+		t.Errorf("Want unhealthy status, got %+v", status)
+	}
+
+	// the next attempt is a login, since there is no
+	// token to renew.
+	r.refresh(noContext)
+	if auth.renews != 0 || auth.logins != 2 {
+		t.Errorf("Want 0 renews and 2 logins, got %d renews and %d logins", auth.renews, auth.logins)
+	}
+	if got := r.interval(); got > 2*time.Second || got < time.Second {
+		t.Errorf("Want retry within 1s to 2s, got %v", got)
+	}
+
+	auth.loginErr = nil
+	r.refresh(noContext)
+	if status := r.Health(); !status.Healthy || status.Failures != 0 {
+		t.Errorf("Want healthy status, got %+v", status)
+	}
+	if got := r.interval(); got < 54*time.Minute {
+		t.Errorf("Want renewal interval, got %v", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{n: 1, want: time.Second},
+		{n: 2, want: 2 * time.Second},
+		{n: 5, want: 16 * time.Second},
+		{n: 10, want: maxBackoff},
+		{n: 100, want: maxBackoff},
+	}
+	for _, test := range tests {
+		if got := backoff(test.n); got > test.want || got < test.want/2 {
+			t.Errorf("Want backoff %v with jitter, got %v", test.want, got)
+		}
+	}
+}
+
+// Test the static token is only revoked if enabled.
+func TestStatic_Revoke(t *testing.T) {
+	var revoked bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/token/revoke-self" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		revoked = true
+		w.WriteHeader(204)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	client.SetToken("8609694a-cdbc-db9b-d345-e782dbb562ed")
+
+	if err := NewStatic(client, 0, false).Revoke(noContext); err != nil {
+		t.Error(err)
+	}
+	if revoked {
+		t.Errorf("Want static token revocation disabled by default")
+	}
+
+	if err := NewStatic(client, 0, true).Revoke(noContext); err != nil {
+		t.Error(err)
+	}
+	if !revoked {
+		t.Errorf("Want static token revoked")
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package token
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/sirupsen/logrus"
+)
+
+// Name that identifies the static token auth method, which
+// uses the VAULT_TOKEN provided by the operator.
+const Name = "token"
+
+func init() {
+	Register(Name, func(client *api.Client, prefix string) (Authenticator, error) {
+		config := struct {
+			TTL    time.Duration `envconfig:"VAULT_TOKEN_TTL"`
+			Revoke bool          `envconfig:"VAULT_TOKEN_REVOKE"`
+		}{}
+		if err := Process(prefix, &config); err != nil {
+			return nil, err
+		}
+		// the token of a named vault backend is read from
+		// the prefixed VAULT_TOKEN when the client is created.
+		if prefix != "" && client.Token() == "" {
+			return nil, fmt.Errorf("token: missing %s", prefixed(prefix, "VAULT_TOKEN"))
+		}
+		return NewStatic(client, config.TTL, config.Revoke), nil
+	})
+}
+
+// static implements the static token auth method.
+type static struct {
+	*Lease
+	client *api.Client
+	ttl    time.Duration
+	revoke bool
+}
+
+// NewStatic returns an Authenticator for the static token
+// configured in the client. If the token is renewable, it
+// is renewed for the ttl or, if the ttl is zero, for the
+// default ttl of the token. The token is owned by the
+// operator, and is only revoked if revoke is true.
+func NewStatic(client *api.Client, ttl time.Duration, revoke bool) Authenticator {
+	return &static{
+		Lease:  NewLease(client, ttl),
+		client: client,
+		ttl:    ttl,
+		revoke: revoke,
+	}
+}
+
+func (s *static) Login(ctx context.Context) error {
+	if s.client.Token() == "" {
+		logrus.Warnln("vault: missing vault token")
+		return nil
+	}
+	// the token lease is unknown, and is looked up so
+	// that renewal can be scheduled before the token
+	// expires.
+	if err := s.Lookup(ctx); err != nil {
+		logrus.WithError(err).
+			Warnln("vault: cannot lookup token")
+		return nil
+	}
+	logrus.WithField("ttl", s.TTL()).
+		WithField("renewable", s.Renewable()).
+		Debugln("vault: token lookup succeeded")
+	return nil
+}
+
+func (s *static) Renew(ctx context.Context) error {
+	logrus.Debugf("vault: refreshing token: increment %v", s.ttl)
+	switch err := s.Lease.Renew(ctx); err {
+	case nil:
+		logrus.Debugf("vault: refreshing token succeeded")
+	case ErrNotRenewable:
+		logrus.Debugf("vault: token is not renewable")
+	case ErrTTLCapped:
+		// the static token cannot be replaced, and is
+		// renewed until it reaches its max ttl.
+		logrus.WithField("ttl", s.TTL()).
+			Warnln("vault: token is approaching its max ttl")
+	default:
+		logrus.Errorf("vault: refreshing token failed: %s", err)
+		return err
+	}
+	return nil
+}
+
+func (s *static) Revoke(ctx context.Context) error {
+	if !s.revoke {
+		logrus.Debugln("vault: static token revocation disabled")
+		return nil
+	}
+	return s.Lease.Revoke(ctx)
+}
@@ -4,10 +4,44 @@
 
 package token
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
-// Token represents the Vault token and token TTL.
-type Token struct {
-	Token string
-	TTL   time.Duration
-}
+type (
+	// Token represents the Vault token and token TTL.
+	Token struct {
+		Token string
+		TTL   time.Duration
+	}
+
+	// Authenticator authenticates with Vault and manages
+	// the lifecycle of the client token.
+	Authenticator interface {
+		// Login authenticates with Vault and sets the
+		// client token.
+		Login(ctx context.Context) error
+
+		// Renew renews the client token. If the token
+		// cannot be renewed an error is returned, and a
+		// new token must be requested with Login.
+		Renew(ctx context.Context) error
+
+		// Revoke revokes the client token.
+		Revoke(ctx context.Context) error
+
+		// TTL returns the remaining time to live of the
+		// client token, or zero if unknown.
+		TTL() time.Duration
+	}
+
+	// Watcher is an optional interface implemented by an
+	// Authenticator that must login again when an external
+	// credential, such as a token file, changes.
+	Watcher interface {
+		// Watch returns a channel that receives a value
+		// when the credential changes.
+		Watch(ctx context.Context) <-chan struct{}
+	}
+)
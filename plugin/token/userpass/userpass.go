@@ -0,0 +1,131 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package userpass
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/drone/drone-vault/plugin/token"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/sirupsen/logrus"
+)
+
+// Names that identify the auth methods. The userpass and
+// ldap auth methods share the same login api.
+const (
+	Name     = "userpass"
+	NameLDAP = "ldap"
+)
+
+// Config configures the auth method.
+type Config struct {
+	Mount        string        `envconfig:"VAULT_AUTH_MOUNT_POINT"`
+	Username     string        `envconfig:"VAULT_USERNAME"`
+	PasswordFile string        `envconfig:"VAULT_PASSWORD_FILE"`
+	TTL          time.Duration `envconfig:"VAULT_TOKEN_TTL"`
+}
+
+func init() {
+	for _, method := range []string{Name, NameLDAP} {
+		method := method
+		token.Register(method, func(client *api.Client, prefix string) (token.Authenticator, error) {
+			config := Config{}
+			if err := token.Process(prefix, &config, "VAULT_USERNAME", "VAULT_PASSWORD_FILE"); err != nil {
+				return nil, err
+			}
+			return NewRenewer(client, method, config.Mount, config.Username, config.PasswordFile, config.TTL), nil
+		})
+	}
+}
+
+type (
+	// Renewer authenticates with the userpass or ldap auth
+	// method and renews the token.
+	Renewer struct {
+		*token.Lease
+
+		client   *api.Client
+		method   string
+		mount    string
+		username string
+		password string
+	}
+)
+
+// NewRenewer returns a new userpass or ldap token provider
+// that renews the token on expiration. The password is read
+// from the password file on every login.
+func NewRenewer(client *api.Client, method, mount, username, password string, ttl time.Duration) *Renewer {
+	if mount == "" {
+		mount = method
+	}
+	return &Renewer{
+		Lease:    token.NewLease(client, ttl),
+		client:   client,
+		method:   method,
+		mount:    mount,
+		username: username,
+		password: password,
+	}
+}
+
+// Login logs in to Vault with the username and password
+// and sets the client token.
+func (r *Renewer) Login(ctx context.Context) error {
+	path := fmt.Sprintf("auth/%s/login/%s", r.mount, r.username)
+
+	logrus.WithField("username", r.username).
+		Debugf("vault %s: generating new token", r.method)
+
+	b, err := ioutil.ReadFile(r.password)
+	if err != nil {
+		logrus.WithError(err).
+			WithField("path", r.password).
+			Errorf("vault %s: cannot read password", r.method)
+		return err
+	}
+
+	resp, err := r.client.Logical().WriteWithContext(ctx, path,
+		map[string]interface{}{
+			"password": string(bytes.TrimSpace(b)),
+		})
+	if err != nil {
+		logrus.WithError(err).
+			WithField("username", r.username).
+			Errorf("vault %s: cannot request vault token", r.method)
+		return err
+	}
+	if err := r.Set(resp); err != nil {
+		logrus.WithError(err).
+			Errorf("vault %s: cannot read vault token", r.method)
+		return err
+	}
+
+	logrus.WithField("ttl", r.TTL()).
+		Debugf("vault %s: token received", r.method)
+
+	return nil
+}
+
+// Renew renews the Vault token.
+func (r *Renewer) Renew(ctx context.Context) error {
+	logrus.Debugf("vault %s: renewing token", r.method)
+
+	if err := r.Lease.Renew(ctx); err != nil {
+		logrus.WithError(err).
+			Errorf("vault %s: token could not be renewed", r.method)
+		return err
+	}
+
+	logrus.WithField("ttl", r.TTL()).
+		Debugf("vault %s: existing token valid", r.method)
+
+	return nil
+}
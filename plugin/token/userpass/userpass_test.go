@@ -0,0 +1,135 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package userpass
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/drone/drone-vault/plugin/token"
+	"github.com/hashicorp/vault/api"
+)
+
+var noContext = context.Background()
+
+var (
+	renewToken = "s.zREhsyJT79kcuGbfrKsbyo0W"
+	newToken   = "s.OhZm4kQxf6K45Tg0bKNQbTJD"
+)
+
+func TestLogin(t *testing.T) {
+	for _, method := range []string{Name, NameLDAP} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if want := "/v1/auth/" + method + "/login/octocat"; r.URL.Path != want {
+				t.Errorf("Want path %s, got %s", want, r.URL.Path)
+			}
+			in := map[string]string{}
+			json.NewDecoder(r.Body).Decode(&in)
+			if got, want := in["password"], "correct-horse-battery-staple"; got != want {
+				t.Errorf("Want password %q, got %q", want, got)
+			}
+			data, _ := ioutil.ReadFile("testdata/new_token.json")
+			w.Write(data)
+		}))
+
+		client, _ := api.NewClient(&api.Config{Address: ts.URL})
+		client.ClearToken()
+
+		r := NewRenewer(client, method, "", "octocat", "testdata/password", 20*time.Minute)
+		if err := r.Login(noContext); err != nil {
+			t.Error(err)
+		}
+		if got := client.Token(); got != newToken {
+			t.Errorf("Want token %s, got %s", newToken, got)
+		}
+		ts.Close()
+	}
+}
+
+func TestLogin_PasswordError(t *testing.T) {
+	client, _ := api.NewClient(nil)
+	client.ClearToken()
+
+	r := NewRenewer(client, NameLDAP, "", "octocat", "testdata/does-not-exist", time.Hour)
+	err := r.Login(noContext)
+	if _, ok := err.(*os.PathError); !ok {
+		t.Errorf("Expect PathError got %v", err)
+	}
+}
+
+func TestLogin_RequestError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	client.ClearToken()
+
+	r := NewRenewer(client, Name, "", "octocat", "testdata/password", time.Hour)
+	if err := r.Login(noContext); err == nil {
+		t.Errorf("Expect request error")
+	}
+}
+
+func TestRenew(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/token/renew-self" {
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		data, _ := ioutil.ReadFile("testdata/renew_token.json")
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	client.SetToken(renewToken)
+
+	r := NewRenewer(client, Name, "", "octocat", "testdata/password", 20*time.Minute)
+	if err := r.Renew(noContext); err != nil {
+		t.Error(err)
+	}
+	if got := client.Token(); got != renewToken {
+		t.Errorf("Want token %s, got %s", renewToken, got)
+	}
+}
+
+// Test the token cannot be renewed when the renewed ttl is
+// lower than requested, and a new token is requested.
+func TestRenew_LowerTTL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data []byte
+		switch r.URL.Path {
+		case "/v1/auth/token/renew-self":
+			data, _ = ioutil.ReadFile("testdata/renew_lower_ttl.json")
+		case "/v1/auth/ldap-corp/login/octocat":
+			data, _ = ioutil.ReadFile("testdata/new_token.json")
+		default:
+			t.Errorf("Invalid path, %v", r.URL.Path)
+		}
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{Address: ts.URL})
+	client.SetToken(renewToken)
+
+	r := NewRenewer(client, NameLDAP, "ldap-corp", "octocat", "testdata/password", 20*time.Minute)
+	if err := r.Renew(noContext); err != token.ErrTTLCapped {
+		t.Errorf("Want ttl capped error, got %v", err)
+	}
+	if err := r.Login(noContext); err != nil {
+		t.Error(err)
+	}
+	if got := client.Token(); got != newToken {
+		t.Errorf("Want token %s, got %s", newToken, got)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// minimum interval between cache refreshes.
+	warmMinInterval = time.Second
+
+	// interval between attempts to read a secret
+	// that could not be prefetched.
+	warmRetryInterval = 30 * time.Second
+)
+
+// Warmer prefetches secrets into the cache.
+type Warmer interface {
+	// Warm prefetches the secret paths into the cache and
+	// refreshes the secrets before they expire, until the
+	// context is canceled.
+	Warm(ctx context.Context, paths []string) error
+}
+
+// LoadPaths reads the secret paths to prefetch from the
+// named file, one path per line. Empty lines and lines
+// starting with a hash are ignored.
+func LoadPaths(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var paths []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	return paths, scanner.Err()
+}
+
+func (p *plugin) Warm(ctx context.Context, paths []string) error {
+	if p.cache == nil {
+		return errors.New("cache warming requires a cache")
+	}
+
+	logrus.WithField("paths", len(paths)).
+		Infoln("vault: cache warming enabled")
+
+	// the time at which each path is next refreshed. paths
+	// are prefetched immediately on startup.
+	next := map[string]time.Time{}
+	for {
+		now := time.Now()
+		wake := now.Add(p.maxAge)
+		for _, path := range paths {
+			if at, ok := next[path]; ok && now.Before(at) {
+				if at.Before(wake) {
+					wake = at
+				}
+				continue
+			}
+			entry, err := p.refresh(p.locate(path, ""))
+			if err != nil {
+				logrus.WithError(err).
+					WithField("secret", path).
+					Warnln("vault: cannot prefetch secret")
+				next[path] = now.Add(warmRetryInterval)
+			} else {
+				// refresh the secret once two thirds of its
+				// time to live has elapsed, so that it is
+				// never served expired.
+				ttl := entry.Expires.Sub(entry.Created)
+				next[path] = entry.Created.Add(ttl * 2 / 3)
+				logrus.WithField("secret", path).
+					WithField("ttl", ttl).
+					Debugln("vault: secret prefetched")
+			}
+			if next[path].Before(wake) {
+				wake = next[path]
+			}
+		}
+
+		delay := time.Until(wake)
+		if delay < warmMinInterval {
+			delay = warmMinInterval
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 Drone.IO Inc. All rights reserved.
+// Use of this source code is governed by the Polyform License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drone/drone-vault/plugin/cache"
+	"github.com/hashicorp/vault/api"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadPaths(t *testing.T) {
+	got, err := LoadPaths("testdata/warm.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	want := []string{"secret/docker", "secret/data/npm"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestWarm(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out, _ := ioutil.ReadFile("testdata/secret.json")
+		w.Write(out)
+	}))
+	defer ts.Close()
+
+	client, _ := api.NewClient(&api.Config{
+		Address:    ts.URL,
+		MaxRetries: 1,
+	})
+
+	c := cache.NewMemory()
+	p := New(client, false, WithCache(c, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := p.Warm(ctx, []string{"secret/docker"})
+	if err != context.DeadlineExceeded {
+		t.Errorf("Want deadline exceeded, got %v", err)
+	}
+
+	entry, ok := c.Get("secret/docker")
+	if !ok {
+		t.Errorf("Want secret prefetched")
+		return
+	}
+	if got, want := entry.Data["username"], "david"; got != want {
+		t.Errorf("Want username %q, got %q", want, got)
+	}
+}
+
+func TestWarm_NoCache(t *testing.T) {
+	p := New(nil, false)
+	if err := p.Warm(noContext, []string{"secret/docker"}); err == nil {
+		t.Errorf("Expect error")
+	}
+}